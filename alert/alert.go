@@ -0,0 +1,218 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/config"
+)
+
+type Severity int
+
+const (
+	INFO Severity = iota
+	WARN
+	CRITICAL
+)
+
+func (s Severity) String() string {
+	switch s {
+	case INFO:
+		return "info"
+	case WARN:
+		return "warn"
+	case CRITICAL:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "info":
+		return INFO, nil
+	case "", "warn", "warning":
+		return WARN, nil
+	case "critical":
+		return CRITICAL, nil
+	default:
+		return INFO, fmt.Errorf("Unknown alert severity %s", name)
+	}
+}
+
+// Alert of a relayer incident, alerts with the same key are deduplicated within the cooldown window
+type Alert struct {
+	Key        string
+	Severity   Severity
+	Title      string
+	Message    string
+	Time       time.Time
+	Suppressed int `json:",omitempty"` // duplicated alerts suppressed since the last one sent
+}
+
+func (a *Alert) String() string {
+	text := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(a.Severity.String()), a.Title, a.Message)
+	if a.Suppressed > 0 {
+		text += fmt.Sprintf(" (%d duplicates suppressed)", a.Suppressed)
+	}
+	return text
+}
+
+// Sink delivers the alerts
+type Sink interface {
+	Name() string
+	Send(*Alert) error
+}
+
+type sink struct {
+	Sink
+	severity Severity // min severity to deliver
+}
+
+type record struct {
+	time       time.Time
+	severity   Severity
+	suppressed int
+}
+
+// Dispatcher routes alerts to the sinks
+type Dispatcher struct {
+	sync.Mutex
+	sinks    []sink
+	cooldown time.Duration
+	records  map[string]*record
+	ch       chan *Alert
+}
+
+// Default cooldown of duplicated alerts
+const COOLDOWN = 10 * time.Minute
+
+func New(conf *config.AlertConfig) (d *Dispatcher, err error) {
+	d = &Dispatcher{cooldown: COOLDOWN, records: map[string]*record{}, ch: make(chan *Alert, 100)}
+	if conf == nil {
+		return
+	}
+	if conf.Cooldown > 0 {
+		d.cooldown = time.Duration(conf.Cooldown) * time.Second
+	}
+	for _, c := range conf.Sinks {
+		s := sink{}
+		s.severity, err = ParseSeverity(c.Severity)
+		if err != nil {
+			return
+		}
+		switch c.Type {
+		case "webhook":
+			s.Sink = &WebhookSink{Url: c.Url}
+		case "slack":
+			s.Sink = &SlackSink{Url: c.Url}
+		case "smtp":
+			s.Sink = &SmtpSink{Host: c.Host, Port: c.Port, Username: c.Username, Password: c.Password, From: c.From, To: c.To}
+		case "file":
+			s.Sink = &FileSink{Path: config.GetConfigPath("", c.Path)}
+		default:
+			return nil, fmt.Errorf("Unknown alert sink type %s", c.Type)
+		}
+		d.sinks = append(d.sinks, s)
+	}
+	return
+}
+
+// Start delivering alerts until the context is done
+func (d *Dispatcher) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				// Flush the alerts raised already
+				for {
+					select {
+					case a := <-d.ch:
+						d.deliver(a)
+					default:
+						return
+					}
+				}
+			case a := <-d.ch:
+				d.deliver(a)
+			}
+		}
+	}()
+}
+
+// Raise the alert, duplicates of the key within the cooldown window are dropped unless escalated
+func (d *Dispatcher) Raise(a *Alert) {
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+	d.Lock()
+	r := d.records[a.Key]
+	if r != nil && a.Severity <= r.severity && a.Time.Sub(r.time) < d.cooldown {
+		r.suppressed++
+		d.Unlock()
+		return
+	}
+	if r != nil {
+		a.Suppressed = r.suppressed
+	}
+	d.records[a.Key] = &record{time: a.Time, severity: a.Severity}
+	d.Unlock()
+
+	select {
+	case d.ch <- a:
+	default:
+		log.Warn("Alert queue is full, dropping alert", "key", a.Key, "title", a.Title)
+	}
+}
+
+func (d *Dispatcher) deliver(a *Alert) {
+	for _, s := range d.sinks {
+		if a.Severity < s.severity {
+			continue
+		}
+		err := s.Send(a)
+		if err != nil {
+			log.Error("Failed to send alert", "sink", s.Name(), "key", a.Key, "err", err)
+		}
+	}
+}
+
+var dispatcher *Dispatcher
+
+// Init the default dispatcher
+func Init(ctx context.Context, wg *sync.WaitGroup, conf *config.AlertConfig) (err error) {
+	d, err := New(conf)
+	if err != nil {
+		return
+	}
+	d.Start(ctx, wg)
+	dispatcher = d
+	return
+}
+
+// Raise the alert with the default dispatcher, the alert is logged in any case
+func Raise(severity Severity, key, title, format string, args ...interface{}) {
+	a := &Alert{Key: key, Severity: severity, Title: title, Message: fmt.Sprintf(format, args...)}
+	switch severity {
+	case CRITICAL:
+		log.Error("Alert", "key", key, "title", title, "message", a.Message)
+	case WARN:
+		log.Warn("Alert", "key", key, "title", title, "message", a.Message)
+	default:
+		log.Info("Alert", "key", key, "title", title, "message", a.Message)
+	}
+	if dispatcher != nil {
+		dispatcher.Raise(a)
+	}
+}
@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/top/top-relayer/config"
+)
+
+// Sink recording the alerts delivered
+type recordSink struct {
+	sync.Mutex
+	alerts []*Alert
+}
+
+func (s *recordSink) Name() string { return "record" }
+
+func (s *recordSink) Send(a *Alert) error {
+	s.Lock()
+	defer s.Unlock()
+	s.alerts = append(s.alerts, a)
+	return nil
+}
+
+// Alerts queued by the dispatcher
+func queued(d *Dispatcher) (list []*Alert) {
+	for {
+		select {
+		case a := <-d.ch:
+			list = append(list, a)
+		default:
+			return
+		}
+	}
+}
+
+func TestDispatcherCooldown(t *testing.T) {
+	d, err := New(&config.AlertConfig{Cooldown: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	raise := func(key string, severity Severity, offset time.Duration) {
+		d.Raise(&Alert{Key: key, Severity: severity, Title: key, Time: start.Add(offset)})
+	}
+	raise("lag", WARN, 0)
+	raise("lag", WARN, 10*time.Second)
+	raise("lag", INFO, 20*time.Second)
+	raise("reset", WARN, 20*time.Second)
+	if list := queued(d); len(list) != 2 || list[0].Key != "lag" || list[1].Key != "reset" {
+		t.Fatalf("expect duplicates within cooldown dropped, got %d alerts", len(list))
+	}
+
+	// Escalated alerts are sent within the cooldown
+	raise("lag", CRITICAL, 30*time.Second)
+	list := queued(d)
+	if len(list) != 1 || list[0].Severity != CRITICAL || list[0].Suppressed != 2 {
+		t.Fatalf("expect escalated alert sent with 2 suppressed, got %+v", list)
+	}
+	raise("lag", WARN, 40*time.Second)
+	if list = queued(d); len(list) != 0 {
+		t.Fatal("expect lower severity dropped after escalation")
+	}
+
+	// Sent again once the cooldown passed
+	raise("lag", WARN, 91*time.Second)
+	if list = queued(d); len(list) != 1 || list[0].Suppressed != 1 {
+		t.Fatalf("expect alert sent after cooldown with 1 suppressed, got %+v", list)
+	}
+}
+
+func TestDispatcherSeverity(t *testing.T) {
+	warn, critical := &recordSink{}, &recordSink{}
+	d := &Dispatcher{sinks: []sink{{warn, WARN}, {critical, CRITICAL}}}
+	for _, severity := range []Severity{INFO, WARN, CRITICAL} {
+		d.deliver(&Alert{Key: "key", Severity: severity})
+	}
+	if len(warn.alerts) != 2 || len(critical.alerts) != 1 || critical.alerts[0].Severity != CRITICAL {
+		t.Fatalf("expect alerts routed by min severity, got %d warn and %d critical", len(warn.alerts), len(critical.alerts))
+	}
+}
+
+func TestNewDispatcher(t *testing.T) {
+	d, err := New(&config.AlertConfig{Sinks: []*config.AlertSinkConfig{
+		{Type: "webhook", Url: "http://localhost"}, {Type: "slack", Url: "http://localhost", Severity: "critical"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.cooldown != COOLDOWN || len(d.sinks) != 2 || d.sinks[0].severity != WARN || d.sinks[1].severity != CRITICAL {
+		t.Fatal("expect default cooldown and sink severities")
+	}
+	if _, err = New(&config.AlertConfig{Sinks: []*config.AlertSinkConfig{{Type: "pager"}}}); err == nil {
+		t.Fatal("expect unknown sink type rejected")
+	}
+	if _, err = New(&config.AlertConfig{Sinks: []*config.AlertSinkConfig{{Type: "file", Severity: "urgent"}}}); err == nil {
+		t.Fatal("expect unknown severity rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+func post(url string, body interface{}) (err error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Unexpected response status %s", resp.Status)
+	}
+	return
+}
+
+// WebhookSink posts the alert in json
+type WebhookSink struct {
+	Url string
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Send(a *Alert) error {
+	return post(s.Url, a)
+}
+
+// SlackSink posts the alert to Slack compatible incoming webhooks
+type SlackSink struct {
+	Url string
+}
+
+func (s *SlackSink) Name() string { return "slack" }
+
+func (s *SlackSink) Send(a *Alert) error {
+	return post(s.Url, map[string]string{"text": a.String()})
+}
+
+// SmtpSink sends the alert with email
+type SmtpSink struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (s *SmtpSink) Name() string { return "smtp" }
+
+func (s *SmtpSink) Send(a *Alert) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	body := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: [%s] %s\r\n\r\n%s\r\n",
+		s.From, strings.Join(s.To, ", "), strings.ToUpper(a.Severity.String()), a.Title, a.String(),
+	)
+	return smtp.SendMail(fmt.Sprintf("%s:%d", s.Host, s.Port), auth, s.From, s.To, []byte(body))
+}
+
+// FileSink appends the alert to a local file in json lines
+type FileSink struct {
+	sync.Mutex
+	Path string
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+func (s *FileSink) Send(a *Alert) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	data, err := json.Marshal(a)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return
+}
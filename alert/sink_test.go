@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Server recording the posted json bodies, responds with the status
+func postServer(t *testing.T, status int) (*httptest.Server, *[]map[string]interface{}) {
+	bodies := &[]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*bodies = append(*bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestWebhookSinks(t *testing.T) {
+	a := &Alert{Key: "reset/1/0", Severity: CRITICAL, Title: "Header sync reset", Message: "rollback to 100", Suppressed: 3}
+
+	server, bodies := postServer(t, http.StatusOK)
+	if err := (&WebhookSink{Url: server.URL}).Send(a); err != nil {
+		t.Fatal(err)
+	}
+	if body := (*bodies)[0]; body["Key"] != a.Key || body["Severity"] != "critical" || body["Suppressed"] != float64(3) {
+		t.Fatalf("expect alert posted in json, got %v", body)
+	}
+
+	if err := (&SlackSink{Url: server.URL}).Send(a); err != nil {
+		t.Fatal(err)
+	}
+	if text := (*bodies)[1]["text"]; text != "[CRITICAL] Header sync reset: rollback to 100 (3 duplicates suppressed)" {
+		t.Fatalf("expect slack text, got %v", text)
+	}
+
+	failed, _ := postServer(t, http.StatusInternalServerError)
+	if err := (&WebhookSink{Url: failed.URL}).Send(a); err == nil {
+		t.Fatal("expect error on failed response status")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	s := &FileSink{Path: path}
+	for _, key := range []string{"a", "b"} {
+		if err := s.Send(&Alert{Key: key, Severity: WARN}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect alerts appended in json lines, got %q", data)
+	}
+	a := map[string]interface{}{}
+	if err = json.Unmarshal([]byte(lines[1]), &a); err != nil || a["Key"] != "b" || a["Severity"] != "warn" {
+		t.Fatalf("expect second alert decoded, got %v %v", a, err)
+	}
+}
@@ -1,50 +1,120 @@
 package base
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 const (
 	TOP uint64 = 0
 	ETH uint64 = 1
 	BSC uint64 = 2
+)
+
+// Chain families, chains of the same family share the listener, submitter and header codec
+const (
+	FAMILY_TOP    = "top"
+	FAMILY_ETH    = "eth"
+	FAMILY_PARLIA = "parlia" // eth compatible with parlia consensus, like BSC
+)
+
+// Chain describes the sync policy of a registered chain
+type Chain struct {
+	Id           uint64
+	Name         string
+	Family       string
+	BlocksToWait uint64 // confirmations before the header is synced
+	BlocksToSkip uint64 // blocks to skip back on a possible fork
+	Epoch        uint64 // epoch length of validator set rotation, zero if not applicable
+	NetworkId    uint64 // chain id returned by nodes with eth_chainId, zero to skip the check
+	Contract     string // default light client contract of TOP deployed on the chain
+	TopContract  string // default light client contract of the chain deployed on TOP
+}
 
-	ENV = "mainnet"
+var (
+	chains     = map[uint64]Chain{}
+	chainsLock sync.RWMutex
 )
 
+func init() {
+	SetNetwork(MAINNET)
+}
+
+// Register adds or replaces the chain in registry
+func Register(chain Chain) {
+	chainsLock.Lock()
+	defer chainsLock.Unlock()
+	chains[chain.Id] = chain
+}
+
+func GetChain(id uint64) (chain Chain, ok bool) {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+	chain, ok = chains[id]
+	return
+}
+
+// Chains returns the registered chain ids in order
+func Chains() (ids []uint64) {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+	for id := range chains {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return
+}
+
 func GetChainName(id uint64) string {
-	switch id {
-	case TOP:
-		return "Top"
-	case ETH:
-		return "Ethereum"
-	case BSC:
-		return "Bsc"
-	default:
+	chain, ok := GetChain(id)
+	if !ok {
 		return fmt.Sprintf("Unknown(%d)", id)
 	}
+	return chain.Name
+}
+
+func Family(id uint64) string {
+	chain, _ := GetChain(id)
+	return chain.Family
 }
 
 func BlocksToSkip(chainId uint64) uint64 {
-	switch chainId {
-	case ETH:
-		return 8
-	case BSC:
-		return 17
-	default:
+	chain, ok := GetChain(chainId)
+	if !ok || chain.BlocksToSkip == 0 {
 		return 1
 	}
+	return chain.BlocksToSkip
 }
 
 func BlocksToWait(chainId uint64) uint64 {
-	switch chainId {
-	case ETH:
-		return 12
-	case BSC:
-		return 21
-	default:
+	chain, ok := GetChain(chainId)
+	if !ok || chain.BlocksToWait == 0 {
 		return 100000000
 	}
+	return chain.BlocksToWait
+}
+
+// Network chain id expected from the nodes, zero if unknown
+func NetworkId(chainId uint64) uint64 {
+	chain, _ := GetChain(chainId)
+	return chain.NetworkId
+}
+
+// Default light client contract on the chain, empty if not known
+func Contract(chainId uint64) string {
+	chain, _ := GetChain(chainId)
+	return chain.Contract
+}
+
+// Default light client contract of the chain on TOP, empty if not known
+func TopContract(chainId uint64) string {
+	chain, _ := GetChain(chainId)
+	return chain.TopContract
 }
 
-var CHAINS = []uint64{
-	TOP, ETH, BSC,
+// Epoch length of chains rotating validator set with epoch headers, zero if not applicable
+func Epoch(chainId uint64) uint64 {
+	chain, _ := GetChain(chainId)
+	return chain.Epoch
 }
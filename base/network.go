@@ -0,0 +1,83 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Network profiles
+const (
+	MAINNET = "mainnet"
+	TESTNET = "testnet"
+	DEVNET  = "devnet"
+	LOCAL   = "local"
+)
+
+// Network profile with its own chain registry, chain ids are mapped to the network chain ids of the environment,
+// and each chain carries the confirmation depths of the environment. Light client contracts are deployment specific,
+// none is built in, they are configured per header sync direction or as defaults with the registry overrides.
+type Network struct {
+	Name   string
+	Chains []Chain
+}
+
+var (
+	networks = map[string]*Network{
+		MAINNET: {Name: MAINNET, Chains: []Chain{
+			{Id: TOP, Name: "Top", Family: FAMILY_TOP, BlocksToWait: 100000000, BlocksToSkip: 1},
+			{Id: ETH, Name: "Ethereum", Family: FAMILY_ETH, BlocksToWait: 12, BlocksToSkip: 8, NetworkId: 1},
+			{Id: BSC, Name: "Bsc", Family: FAMILY_PARLIA, BlocksToWait: 21, BlocksToSkip: 17, Epoch: 200, NetworkId: 56},
+		}},
+		TESTNET: {Name: TESTNET, Chains: []Chain{
+			{Id: TOP, Name: "Top", Family: FAMILY_TOP, BlocksToWait: 100000000, BlocksToSkip: 1},
+			{Id: ETH, Name: "Goerli", Family: FAMILY_ETH, BlocksToWait: 12, BlocksToSkip: 8, NetworkId: 5},
+			{Id: BSC, Name: "BscTestnet", Family: FAMILY_PARLIA, BlocksToWait: 15, BlocksToSkip: 11, Epoch: 200, NetworkId: 97},
+		}},
+		DEVNET: {Name: DEVNET, Chains: []Chain{
+			{Id: TOP, Name: "Top", Family: FAMILY_TOP, BlocksToWait: 100000000, BlocksToSkip: 1},
+			{Id: ETH, Name: "Goerli", Family: FAMILY_ETH, BlocksToWait: 6, BlocksToSkip: 4, NetworkId: 5},
+			{Id: BSC, Name: "BscTestnet", Family: FAMILY_PARLIA, BlocksToWait: 6, BlocksToSkip: 4, Epoch: 200, NetworkId: 97},
+		}},
+		LOCAL: {Name: LOCAL, Chains: []Chain{
+			{Id: TOP, Name: "Top", Family: FAMILY_TOP, BlocksToWait: 100000000, BlocksToSkip: 1},
+			{Id: ETH, Name: "Ethereum", Family: FAMILY_ETH, BlocksToWait: 1, BlocksToSkip: 1},
+			{Id: BSC, Name: "Bsc", Family: FAMILY_PARLIA, BlocksToWait: 1, BlocksToSkip: 1, Epoch: 200},
+		}},
+	}
+	network = MAINNET
+)
+
+// Env returns the selected network profile name
+func Env() string {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+	return network
+}
+
+// Networks returns the names of the network profiles in order
+func Networks() (names []string) {
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// SetNetwork selects the network profile and resets the chain registry to the chains of the profile
+func SetNetwork(name string) error {
+	if name == "" {
+		name = MAINNET
+	}
+	profile, ok := networks[name]
+	if !ok {
+		return fmt.Errorf("Unknown network %s, should be one of %v", name, Networks())
+	}
+	chainsLock.Lock()
+	network = name
+	chains = map[uint64]Chain{}
+	for _, chain := range profile.Chains {
+		chains[chain.Id] = chain
+	}
+	chainsLock.Unlock()
+	return nil
+}
@@ -1,2 +1,5 @@
 #!/bin/bash
-go build -tags $1 -o server .
+# Network profile is selected at runtime with config env or --network flag, so one binary serves every network.
+# The build tag only selects the bridge-common chain constants. With bridge-common v0.0.54 only the devnet tag
+# compiles, the mainnet and testnet tags fail inside bridge-common until it is upgraded.
+go build -tags ${1:-devnet} -o server .
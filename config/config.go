@@ -1,12 +1,10 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
+	"reflect"
 
-	"github.com/polynetwork/bridge-common/util"
 	"github.com/polynetwork/bridge-common/wallet"
 	"github.com/top/top-relayer/base"
 )
@@ -15,16 +13,26 @@ var (
 	CONFIG      *Config
 	WALLET_PATH string
 	CONFIG_PATH string
+	NETWORK     string // network profile selected by flag, overrides the config env
 )
 
 type Config struct {
-	Env    string
+	Env    string // network profile: mainnet, testnet, devnet or local
 	Top    *TopChainConfig
 	Chains map[uint64]*ChainConfig
 
+	// Extra chains to register or builtin chains to override, keyed by chain id
+	Registry map[uint64]*base.Chain
+
 	// Http
-	Host string
-	Port int
+	Host       string
+	Port       int
+	AdminToken string // Token required by the http admin actions, admin actions are disabled if empty
+
+	// Local sync state store path
+	StorePath string
+
+	Alert *AlertConfig
 
 	ValidMethods []string
 	validMethods map[string]bool
@@ -41,17 +49,25 @@ func GetConfigPath(path, file string) string {
 }
 
 func New(path string) (config *Config, err error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("Read config file error %v", err)
+	config, problems, err := Load(path)
+	if err == nil && len(problems) > 0 {
+		err = problems
 	}
+	return
+}
+
+// Load the json, yaml or toml config file with environment overrides, unknown fields are reported as problems
+func Load(path string) (config *Config, problems Problems, err error) {
 	config = &Config{chains: map[uint64]bool{}}
-	err = json.Unmarshal(data, config)
+	fields, err := read(path, config, true)
 	if err != nil {
-		return nil, fmt.Errorf("Parse config file error %v", err)
+		return nil, nil, fmt.Errorf("Load config file error %v", err)
+	}
+	for _, field := range fields {
+		problems.Add("Unknown config field %s", field)
 	}
-	if config.Env != base.ENV {
-		util.Fatal("Config env(%s) and build env(%s) does not match!", config.Env, base.ENV)
+	if NETWORK != "" {
+		config.Env = NETWORK
 	}
 
 	methods := map[string]bool{}
@@ -66,6 +82,26 @@ func New(path string) (config *Config, err error) {
 	return
 }
 
+type AlertConfig struct {
+	Cooldown int // Seconds to suppress duplicated alerts, default 600
+	Sinks    []*AlertSinkConfig
+}
+
+type AlertSinkConfig struct {
+	Type     string // webhook, slack, smtp or file
+	Severity string // Min severity to deliver: info, warn or critical, default warn
+	Url      string // webhook and slack url
+	Path     string // file path
+
+	// smtp
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
 type ChainConfig struct {
 	ChainId     uint64
 	Nodes       []string
@@ -75,7 +111,13 @@ type ChainConfig struct {
 	CheckFee    bool
 	Defer       int
 	Wallet      *wallet.Config
-	HeaderSync  [2]*HeaderSyncConfig // 0:chain -> ch -> top; 1: top -> ch -> chain
+	Gas         *GasConfig
+
+	// Native balance thresholds of submitter accounts in wei, submission is paused below critical
+	BalanceWarn     string
+	BalanceCritical string
+
+	HeaderSync [2]*HeaderSyncConfig // 0:chain -> ch -> top; 1: top -> ch -> chain
 }
 
 type ListenerConfig struct {
@@ -92,6 +134,30 @@ type SubmitterConfig struct {
 	ExtraNodes []string
 	HSContract string
 	Wallet     *wallet.Config
+	Gas        *GasConfig
+	Select     string // account selection of the wallet, round-robin or least-busy
+	MinBalance string // accounts with balance below it are skipped, in wei
+	Parallel   bool   // submit consecutive headers from different accounts, only if the light client accepts them out of order
+
+	BalanceWarn     string
+	BalanceCritical string
+}
+
+// Gas strategy of the submitter, prices are in wei
+type GasConfig struct {
+	Strategy    string  // fixed, suggested, eip1559 or percentile, default suggested with legacy txs
+	Price       string  // gas price of fixed strategy
+	Multiplier  float64 // multiplier of node suggested gas price or tip, default 1
+	MaxFee      string  // fee cap of eip1559 strategy, default 3 times of suggested price
+	MaxTip      string  // tip cap of eip1559 and percentile strategies
+	Percentile  int     // percentile of tips in recent blocks, default 50
+	Blocks      int     // recent blocks to sample tips, default 20
+	MaxPrice    string  // hard ceiling of gas price or fee cap
+	LimitMargin float64 // safety margin added to the estimated gas limit, default 0.3
+	MaxLimit    uint64  // hard ceiling of gas limit
+	BumpTimeout int     // seconds before replacing a pending tx with bumped fees, default 60
+	BumpPercent int     // fee bump percent of replacement txs, default 20
+	MaxBumps    int     // max replacements of a pending tx, default 3, negative to disable
 }
 
 type TopChainConfig struct {
@@ -100,6 +166,10 @@ type TopChainConfig struct {
 	ExtraNodes []string
 	HSContract string
 	Wallet     *wallet.Config
+	Gas        *GasConfig
+
+	BalanceWarn     string
+	BalanceCritical string
 }
 
 func (c *TopChainConfig) Fill(o *TopChainConfig) *TopChainConfig {
@@ -125,25 +195,64 @@ type WalletConfig struct {
 }
 
 type HeaderSyncConfig struct {
-	Batch     int
-	Timeout   int
-	Buffer    int
-	Enabled   bool
-	Submitter *SubmitterConfig
+	Batch         int
+	Timeout       int
+	Buffer        int
+	Enabled       bool
+	MaxReorgDepth int  // Max blocks to roll back when searching the common ancestor
+	HeaderWindow  int  // Recently fetched headers kept to detect source chain reorgs
+	LagAlert      int  // Blocks behind source chain tip to raise alert, default 1000
+	StopTimeout   int  // Seconds to wait for buffered headers to be submitted on stop, default 60
+	PauseCheck    int  // Seconds between polls of the light client contract pause flags, default 30
+	Schedule      bool // Submit only the newest header the light client contract accepts, batch is ignored
+	Submitter     *SubmitterConfig
 	*ListenerConfig
 }
 
+// Network profile of the config
+func (c *Config) Network() string {
+	if c.Env == "" {
+		return base.MAINNET
+	}
+	return c.Env
+}
+
 func (c *Config) Active(chain uint64) bool {
 	return c.chains[chain]
 }
 
+// Init prepares the config and makes it the current config
 func (c *Config) Init() (err error) {
+	err = c.Prepare()
+	if err != nil {
+		return
+	}
+	CONFIG = c
+	return
+}
+
+// Prepare applies the network profile and registry overrides and fills in the defaults. The chain registry is global,
+// so it is only rebuilt when the network or the registry overrides differ from the current config.
+func (c *Config) Prepare() (err error) {
+	if CONFIG == nil || c.Network() != base.Env() || !reflect.DeepEqual(c.Registry, CONFIG.Registry) {
+		err = c.initRegistry()
+		if err != nil {
+			return
+		}
+	}
+	c.Env = base.Env()
 	if c.Host == "" {
 		c.Host = "0.0.0.0"
 	}
 	if c.Port == 0 {
 		c.Port = 6500
 	}
+	if c.StorePath == "" {
+		c.StorePath = "store"
+	}
+	if !filepath.IsAbs(c.StorePath) {
+		c.StorePath = GetConfigPath("", c.StorePath)
+	}
 
 	if c.Top != nil {
 		err = c.Top.Init()
@@ -158,8 +267,54 @@ func (c *Config) Init() (err error) {
 			return
 		}
 	}
+	return
+}
 
-	CONFIG = c
+// Chain registry is reset to the network profile before applying the registry overrides
+func (c *Config) initRegistry() (err error) {
+	err = base.SetNetwork(c.Network())
+	if err != nil {
+		return
+	}
+	for id, chain := range c.Registry {
+		if chain == nil {
+			continue
+		}
+		// Unset fields of builtin chains are kept
+		current, ok := base.GetChain(id)
+		if !ok && chain.Family == "" {
+			return fmt.Errorf("Missing family for chain %d in registry", id)
+		}
+		current.Id = id
+		if chain.Name != "" {
+			current.Name = chain.Name
+		}
+		if chain.Family != "" {
+			current.Family = chain.Family
+		}
+		if chain.BlocksToWait > 0 {
+			current.BlocksToWait = chain.BlocksToWait
+		}
+		if chain.BlocksToSkip > 0 {
+			current.BlocksToSkip = chain.BlocksToSkip
+		}
+		if chain.Epoch > 0 {
+			current.Epoch = chain.Epoch
+		}
+		if chain.NetworkId > 0 {
+			current.NetworkId = chain.NetworkId
+		}
+		if chain.Contract != "" {
+			current.Contract = chain.Contract
+		}
+		if chain.TopContract != "" {
+			current.TopContract = chain.TopContract
+		}
+		if current.Name == "" {
+			current.Name = fmt.Sprintf("Chain%d", id)
+		}
+		base.Register(current)
+	}
 	return
 }
 
@@ -170,7 +325,13 @@ func (c *Config) AllowMethod(method string) bool {
 func (c *TopChainConfig) Init() (err error) {
 	c.ChainId = base.TOP
 	if c.Wallet != nil {
+		if len(c.Wallet.Nodes) == 0 {
+			c.Wallet.Nodes = c.Nodes
+		}
 		c.Wallet.Path = GetConfigPath(WALLET_PATH, c.Wallet.Path)
+		for _, p := range c.Wallet.KeyStoreProviders {
+			p.Path = GetConfigPath(WALLET_PATH, p.Path)
+		}
 	}
 
 	return
@@ -217,7 +378,7 @@ func (c *TopChainConfig) FillSubmitter(o *SubmitterConfig) *SubmitterConfig {
 		o.Wallet = c.Wallet
 	} else {
 		o.Wallet.Path = GetConfigPath(WALLET_PATH, o.Wallet.Path)
-		if len(o.Wallet.Nodes) == 0 {
+		if len(o.Wallet.Nodes) == 0 && c.Wallet != nil {
 			o.Wallet.Nodes = c.Wallet.Nodes
 		}
 		for _, p := range o.Wallet.KeyStoreProviders {
@@ -228,6 +389,15 @@ func (c *TopChainConfig) FillSubmitter(o *SubmitterConfig) *SubmitterConfig {
 	if o.HSContract == "" {
 		o.HSContract = c.HSContract
 	}
+	if o.Gas == nil {
+		o.Gas = c.Gas
+	}
+	if o.BalanceWarn == "" {
+		o.BalanceWarn = c.BalanceWarn
+	}
+	if o.BalanceCritical == "" {
+		o.BalanceCritical = c.BalanceCritical
+	}
 	return o
 }
 
@@ -247,27 +417,41 @@ func (c *ChainConfig) Init(chain uint64, top *TopChainConfig) (err error) {
 		}
 	}
 
-	if c.HeaderSync[0] == nil {
-		c.HeaderSync[0].ListenerConfig = c.FillListener(c.HeaderSync[0].ListenerConfig)
-		c.HeaderSync[0].ChainId = chain
-		c.HeaderSync[0].Submitter = top.FillSubmitter(c.HeaderSync[0].Submitter)
+	for i := range c.HeaderSync {
+		if c.HeaderSync[i] == nil {
+			c.HeaderSync[i] = new(HeaderSyncConfig)
+		}
 	}
 
-	if c.HeaderSync[1] == nil {
+	c.HeaderSync[0].ListenerConfig, err = c.FillListener(c.HeaderSync[0].ListenerConfig)
+	if err != nil {
+		return
+	}
+	c.HeaderSync[0].ChainId = chain
+	if top != nil {
+		c.HeaderSync[0].Submitter = top.FillSubmitter(c.HeaderSync[0].Submitter)
+		if c.HeaderSync[0].Submitter.HSContract == "" {
+			// Light client of the chain on TOP
+			c.HeaderSync[0].Submitter.HSContract = base.TopContract(chain)
+		}
 		c.HeaderSync[1].ListenerConfig = top.FillListener(c.HeaderSync[1].ListenerConfig)
-		c.HeaderSync[1].ChainId = base.TOP
-		c.HeaderSync[1].Submitter = c.FillSubmitter(c.HeaderSync[1].Submitter)
 	}
 
+	if c.HeaderSync[1].ListenerConfig == nil {
+		// No top chain configured, reported by validation
+		c.HeaderSync[1].ListenerConfig = new(ListenerConfig)
+	}
+	c.HeaderSync[1].ChainId = base.TOP
+	c.HeaderSync[1].Submitter, err = c.FillSubmitter(c.HeaderSync[1].Submitter)
 	return
 }
 
-func (c *ChainConfig) FillSubmitter(o *SubmitterConfig) *SubmitterConfig {
+func (c *ChainConfig) FillSubmitter(o *SubmitterConfig) (*SubmitterConfig, error) {
 	if o == nil {
 		o = new(SubmitterConfig)
 	}
 	if o.ChainId != 0 && c.ChainId != o.ChainId {
-		util.Fatal("Conflict chain id in config for submitters %d <> %d", o.ChainId, c.ChainId)
+		return nil, fmt.Errorf("Conflict chain id in config for submitters %d <> %d", o.ChainId, c.ChainId)
 	}
 	o.ChainId = c.ChainId
 	if len(o.Nodes) == 0 {
@@ -280,7 +464,7 @@ func (c *ChainConfig) FillSubmitter(o *SubmitterConfig) *SubmitterConfig {
 		o.Wallet = c.Wallet
 	} else {
 		o.Wallet.Path = GetConfigPath(WALLET_PATH, o.Wallet.Path)
-		if len(o.Wallet.Nodes) == 0 {
+		if len(o.Wallet.Nodes) == 0 && c.Wallet != nil {
 			o.Wallet.Nodes = c.Wallet.Nodes
 		}
 		for _, p := range o.Wallet.KeyStoreProviders {
@@ -291,16 +475,29 @@ func (c *ChainConfig) FillSubmitter(o *SubmitterConfig) *SubmitterConfig {
 	if o.HSContract == "" {
 		o.HSContract = c.HSContract
 	}
+	if o.HSContract == "" {
+		// Light client of TOP on the chain
+		o.HSContract = base.Contract(o.ChainId)
+	}
+	if o.Gas == nil {
+		o.Gas = c.Gas
+	}
+	if o.BalanceWarn == "" {
+		o.BalanceWarn = c.BalanceWarn
+	}
+	if o.BalanceCritical == "" {
+		o.BalanceCritical = c.BalanceCritical
+	}
 
-	return o
+	return o, nil
 }
 
-func (c *ChainConfig) FillListener(o *ListenerConfig) *ListenerConfig {
+func (c *ChainConfig) FillListener(o *ListenerConfig) (*ListenerConfig, error) {
 	if o == nil {
 		o = new(ListenerConfig)
 	}
 	if o.ChainId != 0 && c.ChainId != o.ChainId {
-		util.Fatal("Conflict chain id in config for listeners %d <> %d", o.ChainId, c.ChainId)
+		return nil, fmt.Errorf("Conflict chain id in config for listeners %d <> %d", o.ChainId, c.ChainId)
 	}
 	o.ChainId = c.ChainId
 	if len(o.Nodes) == 0 {
@@ -317,5 +514,5 @@ func (c *ChainConfig) FillListener(o *ListenerConfig) *ListenerConfig {
 		o.ListenCheck = c.ListenCheck
 	}
 
-	return o
+	return o, nil
 }
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/top/top-relayer/base"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "relayer-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateWithoutTop(t *testing.T) {
+	path := writeConfig(t, "config.json", `{
+		"Env": "devnet",
+		"Chains": {
+			"1": {
+				"Nodes": ["http://localhost:8545"],
+				"HSContract": "0x0000000000000000000000000000000000000001"
+			}
+		}
+	}`)
+	conf, problems, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("unexpected load problems %v", problems)
+	}
+	conf.ApplyRoles(Roles{1: {HeaderSync: true}})
+	if err = conf.Init(); err != nil {
+		t.Fatalf("init failed %v", err)
+	}
+	problems = conf.Validate()
+	found := false
+	for _, p := range problems {
+		if p == "Top chain nodes are not configured" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("missing top chain problem in %v", problems)
+	}
+	if len(problems) < 2 {
+		t.Fatalf("expect problems of the header sync directions reported together, got %v", problems)
+	}
+	if !strings.Contains(problems.Error(), "Top chain nodes") {
+		t.Fatalf("unexpected problems error %s", problems.Error())
+	}
+}
+
+func TestPrepareKeepsRegistry(t *testing.T) {
+	current := CONFIG
+	t.Cleanup(func() {
+		CONFIG = current
+		base.SetNetwork(base.MAINNET)
+	})
+	registry := map[uint64]*base.Chain{9: {Name: "Extra", Family: base.FAMILY_ETH}}
+	conf := &Config{Env: base.DEVNET, Registry: registry}
+	if err := conf.Init(); err != nil {
+		t.Fatal(err)
+	}
+	// Chain registered by others, like a running role, is not wiped by preparing an unchanged config
+	base.Register(base.Chain{Id: 10, Name: "Running"})
+	next := &Config{Env: base.DEVNET, Registry: map[uint64]*base.Chain{9: {Name: "Extra", Family: base.FAMILY_ETH}}}
+	if err := next.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := base.GetChain(10); !ok {
+		t.Fatal("expect chain registry kept for unchanged network and registry")
+	}
+	if CONFIG != conf {
+		t.Fatal("expect prepare not to replace the current config")
+	}
+	// Registry overrides changed, the registry is rebuilt from the network profile
+	next.Registry = map[uint64]*base.Chain{9: {Name: "Other", Family: base.FAMILY_ETH}}
+	if err := next.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := base.GetChain(10); ok {
+		t.Fatal("expect chain registry rebuilt for changed registry")
+	}
+	if base.GetChainName(9) != "Other" {
+		t.Fatalf("expect registry override applied, got %s", base.GetChainName(9))
+	}
+}
+
+func TestLightClientContracts(t *testing.T) {
+	current := CONFIG
+	t.Cleanup(func() {
+		CONFIG = current
+		base.SetNetwork(base.MAINNET)
+	})
+	raw := `{
+		"Env": "devnet",
+		"Top": {"Nodes": ["http://localhost:8080"]},
+		"Chains": {"1": {"Nodes": ["http://localhost:8545"]}}
+		%s
+	}`
+	cases := []struct {
+		name     string
+		registry string
+		problems []string
+		contract string // light client of TOP on the chain
+		client   string // light client of the chain on TOP
+	}{
+		{
+			name: "none built in",
+			problems: []string{
+				"registry TopContract of chain 1 for the devnet network",
+				"registry Contract of chain 1 for the devnet network",
+			},
+		},
+		{
+			name:     "registry defaults",
+			registry: `, "Registry": {"1": {"Contract": "0x0000000000000000000000000000000000000002", "TopContract": "0x0000000000000000000000000000000000000003"}}`,
+			contract: "0x0000000000000000000000000000000000000002",
+			client:   "0x0000000000000000000000000000000000000003",
+		},
+	}
+	for _, c := range cases {
+		conf, problems, err := Load(writeConfig(t, "config.json", strings.Replace(raw, "%s", c.registry, 1)))
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("%s: load failed %v %v", c.name, err, problems)
+		}
+		conf.ApplyRoles(Roles{1: {HeaderSync: true}})
+		if err = conf.Init(); err != nil {
+			t.Fatalf("%s: init failed %v", c.name, err)
+		}
+		hs := conf.Chains[1].HeaderSync
+		if hs[0].Submitter.HSContract != c.client || hs[1].Submitter.HSContract != c.contract {
+			t.Fatalf("%s: unexpected contracts %q %q", c.name, hs[0].Submitter.HSContract, hs[1].Submitter.HSContract)
+		}
+		report := conf.Validate().Error()
+		for _, p := range c.problems {
+			if !strings.Contains(report, p) {
+				t.Fatalf("%s: expect problem %q in %s", c.name, p, report)
+			}
+		}
+		if len(c.problems) == 0 && strings.Contains(report, "no light client contract") {
+			t.Fatalf("%s: unexpected contract problem in %s", c.name, report)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of environment variables overriding config values, path segments are separated by double underscores,
+// like RELAYER_TOP__NODES=http://node1,http://node2 or RELAYER_CHAINS__1__HSCONTRACT=0x...
+const ENV_PREFIX = "RELAYER_"
+
+// ${NAME}, ${NAME:-default} or ${file:/path/to/secret}
+var placeholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*|file:[^}]+?)(:-([^}]*))?\}`)
+
+// Read the json, yaml or toml file by extension into v, string values are interpolated with environment variables
+// and secret files. Fields not defined in v are returned.
+func read(path string, v interface{}, overrides bool) (fields []string, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Read file error %v", err)
+	}
+	var raw interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		var tree map[string]interface{}
+		err = toml.Unmarshal(data, &tree)
+		raw = tree
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Parse file error %v", err)
+	}
+	raw = normalize(raw)
+	if overrides {
+		raw, err = override(raw, os.Environ())
+		if err != nil {
+			return
+		}
+	}
+	raw, err = resolve(raw, reflect.TypeOf(v))
+	if err != nil {
+		return
+	}
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return nil, fmt.Errorf("Parse file error %v", err)
+	}
+	return UnknownFields(data, v)
+}
+
+// Convert yaml maps to json compatible maps, chain ids are decoded as integer keys by yaml
+func normalize(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = normalize(value)
+		}
+		return list
+	}
+	return raw
+}
+
+// Interpolate the string values, and convert them to the type of the target field if it is not a string
+func resolve(raw interface{}, t reflect.Type) (interface{}, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := raw.(type) {
+	case string:
+		value, err := interpolate(v)
+		if err != nil || t == nil {
+			return value, err
+		}
+		return convert(value, t), nil
+	case map[string]interface{}:
+		for key, value := range v {
+			var elem reflect.Type
+			if t != nil {
+				switch t.Kind() {
+				case reflect.Struct:
+					if field, ok := lookup(t, key); ok {
+						elem = field.Type
+					}
+				case reflect.Map:
+					elem = t.Elem()
+				}
+			}
+			resolved, err := resolve(value, elem)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i, value := range v {
+			resolved, err := resolve(value, elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return raw, nil
+}
+
+// Expand the placeholders in the value
+func interpolate(value string) (string, error) {
+	var err error
+	res := placeholder.ReplaceAllStringFunc(value, func(s string) string {
+		match := placeholder.FindStringSubmatch(s)
+		name, fallback, hasDefault := match[1], match[3], match[2] != ""
+		if strings.HasPrefix(name, "file:") {
+			data, e := ioutil.ReadFile(strings.TrimPrefix(name, "file:"))
+			if e != nil {
+				if !hasDefault && err == nil {
+					err = fmt.Errorf("Read secret file error %v", e)
+				}
+				return fallback
+			}
+			return strings.TrimSpace(string(data))
+		}
+		if env, ok := os.LookupEnv(name); ok {
+			return env
+		}
+		if !hasDefault && err == nil {
+			err = fmt.Errorf("Environment variable %s is not set", name)
+		}
+		return fallback
+	})
+	return res, err
+}
+
+// Convert the string value to the target type, comma separated lists are accepted for string slices.
+// Values which can not be converted are kept, so that the error is reported on decoding.
+func convert(value string, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return value
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			list := []interface{}{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			return list
+		}
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(value), &v) == nil {
+		return v
+	}
+	return value
+}
+
+// Apply the RELAYER_* environment variables, values are interpolated and converted like the file values
+func override(raw interface{}, environ []string) (res interface{}, err error) {
+	sort.Strings(environ)
+	res = raw
+	for _, env := range environ {
+		if !strings.HasPrefix(env, ENV_PREFIX) {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(env, ENV_PREFIX), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		res, err = set(res, strings.Split(kv[0], "__"), kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid config override %s%s: %v", ENV_PREFIX, kv[0], err)
+		}
+	}
+	return
+}
+
+// Set the value at the path, keys are matched case insensitively
+func set(node interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	var err error
+	switch n := node.(type) {
+	case nil:
+		return set(map[string]interface{}{}, keys, value)
+	case map[string]interface{}:
+		key := keys[0]
+		for k := range n {
+			if strings.EqualFold(k, key) {
+				key = k
+				break
+			}
+		}
+		n[key], err = set(n[key], keys[1:], value)
+		return n, err
+	case []interface{}:
+		i, e := strconv.Atoi(keys[0])
+		if e != nil || i < 0 || i > len(n) {
+			return nil, fmt.Errorf("invalid index %s", keys[0])
+		}
+		if i == len(n) {
+			n = append(n, nil)
+		}
+		n[i], err = set(n[i], keys[1:], value)
+		return n, err
+	default:
+		return nil, fmt.Errorf("%s is not an object or list", keys[0])
+	}
+}
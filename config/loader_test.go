@@ -0,0 +1,254 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+type testNode struct {
+	Url   string
+	Extra []string
+}
+
+type testConfig struct {
+	Name   string
+	Port   int
+	Debug  bool
+	Nodes  []string
+	Chains map[string]*testNode
+	*testNode
+}
+
+func TestInterpolate(t *testing.T) {
+	secret := writeConfig(t, "secret", "  password\n")
+	setenv(t, "RELAYER_TEST_HOST", "node.local")
+	os.Unsetenv("RELAYER_TEST_MISSING")
+	cases := []struct {
+		value  string
+		expect string
+		err    bool
+	}{
+		{"plain", "plain", false},
+		{"http://${RELAYER_TEST_HOST}:8545", "http://node.local:8545", false},
+		{"${RELAYER_TEST_MISSING:-fallback}", "fallback", false},
+		{"${RELAYER_TEST_MISSING:-}", "", false},
+		{"${RELAYER_TEST_MISSING}", "", true},
+		{"${file:" + secret + "}", "password", false},
+		{"${file:" + secret + ".missing:-none}", "none", false},
+		{"${file:" + secret + ".missing}", "", true},
+	}
+	for _, c := range cases {
+		value, err := interpolate(c.value)
+		if (err != nil) != c.err {
+			t.Fatalf("interpolate %s expect error %v, got %v", c.value, c.err, err)
+		}
+		if value != c.expect {
+			t.Fatalf("interpolate %s expect %q, got %q", c.value, c.expect, value)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		environ []string
+		expect  testConfig
+		err     bool
+	}{
+		{
+			name:    "top level",
+			raw:     `{"Name": "a", "Port": 1}`,
+			environ: []string{"RELAYER_NAME=b", "RELAYER_PORT=2", "OTHER_NAME=c"},
+			expect:  testConfig{Name: "b", Port: 2},
+		},
+		{
+			name:    "case insensitive",
+			raw:     `{"Name": "a"}`,
+			environ: []string{"RELAYER_name=b", "RELAYER_DEBUG=true"},
+			expect:  testConfig{Name: "b", Debug: true},
+		},
+		{
+			name:    "comma separated list",
+			raw:     `{}`,
+			environ: []string{"RELAYER_NODES=http://a, http://b"},
+			expect:  testConfig{Nodes: []string{"http://a", "http://b"}},
+		},
+		{
+			name:    "nested path",
+			raw:     `{"Chains": {"1": {"Url": "a"}}}`,
+			environ: []string{"RELAYER_CHAINS__1__URL=b", "RELAYER_CHAINS__2__EXTRA=x,y"},
+			expect: testConfig{Chains: map[string]*testNode{
+				"1": {Url: "b"},
+				"2": {Extra: []string{"x", "y"}},
+			}},
+		},
+		{
+			name:    "list index",
+			raw:     `{"Nodes": ["a", "b"]}`,
+			environ: []string{"RELAYER_NODES__1=c", "RELAYER_NODES__2=d"},
+			expect:  testConfig{Nodes: []string{"a", "c", "d"}},
+		},
+		{
+			name:    "invalid list index",
+			raw:     `{"Nodes": ["a"]}`,
+			environ: []string{"RELAYER_NODES__5=c"},
+			err:     true,
+		},
+		{
+			name:    "path through value",
+			raw:     `{"Name": "a"}`,
+			environ: []string{"RELAYER_NAME__X=b"},
+			err:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := writeConfig(t, "config.json", c.raw)
+			for _, env := range os.Environ() {
+				if strings.HasPrefix(env, ENV_PREFIX) {
+					t.Skipf("environment has %s set", env)
+				}
+			}
+			for _, env := range c.environ {
+				kv := strings.SplitN(env, "=", 2)
+				setenv(t, kv[0], kv[1])
+			}
+			var conf testConfig
+			_, err := read(path, &conf, true)
+			if (err != nil) != c.err {
+				t.Fatalf("expect error %v, got %v", c.err, err)
+			}
+			if err == nil && !reflect.DeepEqual(conf, c.expect) {
+				t.Fatalf("expect %+v, got %+v", c.expect, conf)
+			}
+		})
+	}
+}
+
+func TestReadFormats(t *testing.T) {
+	setenv(t, "RELAYER_TEST_PORT", "8080")
+	expect := testConfig{
+		Name:   "relayer",
+		Port:   8080,
+		Debug:  true,
+		Nodes:  []string{"http://a", "http://b"},
+		Chains: map[string]*testNode{"1": {Url: "http://c"}},
+	}
+	files := map[string]string{
+		"config.json": `{
+			"Name": "relayer", "Port": "${RELAYER_TEST_PORT}", "Debug": true,
+			"Nodes": ["http://a", "http://b"], "Chains": {"1": {"Url": "http://c"}}
+		}`,
+		"config.yaml": `
+Name: relayer
+Port: ${RELAYER_TEST_PORT}
+Debug: true
+Nodes:
+  - http://a
+  - http://b
+Chains:
+  1:
+    Url: http://c
+`,
+		"config.toml": `
+Name = "relayer"
+Port = "${RELAYER_TEST_PORT}"
+Debug = true
+Nodes = ["http://a", "http://b"]
+[Chains.1]
+Url = "http://c"
+`,
+	}
+	for name, content := range files {
+		t.Run(filepath.Ext(name), func(t *testing.T) {
+			var conf testConfig
+			fields, err := read(writeConfig(t, name, content), &conf, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fields) > 0 {
+				t.Fatalf("unexpected unknown fields %v", fields)
+			}
+			if !reflect.DeepEqual(conf, expect) {
+				t.Fatalf("expect %+v, got %+v", expect, conf)
+			}
+		})
+	}
+}
+
+func TestReadMissingEnv(t *testing.T) {
+	os.Unsetenv("RELAYER_TEST_MISSING")
+	var conf testConfig
+	_, err := read(writeConfig(t, "config.json", `{"Name": "${RELAYER_TEST_MISSING}"}`), &conf, false)
+	if err == nil || !strings.Contains(err.Error(), "RELAYER_TEST_MISSING") {
+		t.Fatalf("expect missing env var error, got %v", err)
+	}
+}
+
+func TestReadInvalidFile(t *testing.T) {
+	var conf testConfig
+	if _, err := read(writeConfig(t, "config.yaml", "Name: [a"), &conf, false); err == nil {
+		t.Fatal("expect parse error")
+	}
+	if _, err := read(filepath.Join(os.TempDir(), "relayer-config-missing.json"), &conf, false); err == nil {
+		t.Fatal("expect read error")
+	}
+	if _, err := read(writeConfig(t, "config.json", `{"Port": "abc"}`), &conf, false); err == nil {
+		t.Fatal("expect decode error")
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	cases := []struct {
+		data   string
+		fields []string
+	}{
+		{`{"Name": "a", "name": "b"}`, nil},
+		{`{"Nmae": "a"}`, []string{"Nmae"}},
+		{`{"Url": "a", "Extra": []}`, nil},
+		{`{"Chains": {"1": {"Url": "a", "Urls": []}}}`, []string{"Chains.1.Urls"}},
+		{`{"Chains": {"1": {"Uri": "a"}, "2": {"Extr": []}}}`, []string{"Chains.1.Uri", "Chains.2.Extr"}},
+		{`{"testNode": {}}`, []string{"testNode"}},
+	}
+	for _, c := range cases {
+		fields, err := UnknownFields([]byte(c.data), &testConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(fields)
+		if len(fields) != len(c.fields) || (len(fields) > 0 && !reflect.DeepEqual(fields, c.fields)) {
+			t.Fatalf("%s expect unknown fields %v, got %v", c.data, c.fields, fields)
+		}
+	}
+}
+
+func TestUnknownFieldsOfConfig(t *testing.T) {
+	fields, err := UnknownFields([]byte(`{
+		"Env": "devnet",
+		"Chains": {"1": {"Nodes": [], "HeaderSync": [{"Batch": 1, "Submitter": {"HSContrat": ""}, "ListenCheck": 1}]}}
+	}`), &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0] != "Chains.1.HeaderSync[0].Submitter.HSContrat" {
+		t.Fatalf("unexpected unknown fields %v", fields)
+	}
+}
@@ -1,9 +1,7 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/top/top-relayer/base"
 )
@@ -15,14 +13,13 @@ type Role struct {
 type Roles map[uint64]Role
 
 func (c *Config) ReadRoles(path string) (err error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("Read roles file error %v", err)
-	}
 	roles := Roles{}
-	err = json.Unmarshal(data, &roles)
+	fields, err := read(path, &roles, false)
 	if err != nil {
-		return fmt.Errorf("Parse roles file error %v", err)
+		return fmt.Errorf("Load roles file error %v", err)
+	}
+	if len(fields) > 0 {
+		return fmt.Errorf("Unknown roles fields %v", fields)
 	}
 	c.ApplyRoles(roles)
 	return
@@ -41,9 +38,20 @@ func (c *Config) ApplyRoles(roles Roles) {
 				chain = new(ChainConfig)
 				c.Chains[id] = chain
 			}
+			for i := range chain.HeaderSync {
+				if chain.HeaderSync[i] == nil {
+					chain.HeaderSync[i] = new(HeaderSyncConfig)
+				}
+			}
 
 			chain.HeaderSync[0].Enabled = role.HeaderSync
 			chain.HeaderSync[1].Enabled = role.HeaderSync
 		}
 	}
 }
+
+// ApplyChains replaces the chain configs and the active chains with those of the reloaded config
+func (c *Config) ApplyChains(o *Config) {
+	c.Chains = o.Chains
+	c.chains = o.chains
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Problems found in config, reported at once
+type Problems []string
+
+func (p *Problems) Add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p Problems) Error() string {
+	return fmt.Sprintf("%d config problems found:\n  - %s", len(p), strings.Join(p, "\n  - "))
+}
+
+// UnknownFields lists the fields in json data which are not defined in the target type, like typos silently ignored by json.Unmarshal
+func UnknownFields(data []byte, v interface{}) (fields []string, err error) {
+	var raw interface{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return
+	}
+	walk("", raw, reflect.TypeOf(v), &fields)
+	return
+}
+
+func walk(path string, raw interface{}, t reflect.Type, fields *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, value := range obj {
+			field, ok := lookup(t, key)
+			if !ok {
+				*fields = append(*fields, join(path, key))
+				continue
+			}
+			walk(join(path, key), value, field.Type, fields)
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, value := range obj {
+			walk(join(path, key), value, t.Elem(), fields)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return
+		}
+		for i, value := range list {
+			walk(fmt.Sprintf("%s[%d]", path, i), value, t.Elem(), fields)
+		}
+	}
+}
+
+// Find the exported field matching the json key case insensitively, fields of embedded structs are promoted
+func lookup(t reflect.Type, key string) (field reflect.StructField, ok bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if field, ok = lookup(embedded, key); ok {
+					return
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
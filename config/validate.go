@@ -0,0 +1,112 @@
+package config
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/top/top-relayer/base"
+)
+
+// Validate checks the initialized config without network access, all problems are reported at once
+func (c *Config) Validate() (problems Problems) {
+	active := false
+	for id := range c.chains {
+		if id != base.TOP {
+			active = true
+		}
+		if _, ok := base.GetChain(id); !ok {
+			problems.Add("Chain %d in roles is not registered", id)
+		}
+	}
+	if active && (c.Top == nil || len(c.Top.Nodes) == 0) {
+		problems.Add("Top chain nodes are not configured")
+	}
+
+	for id, chain := range c.Chains {
+		if !c.Active(id) {
+			continue
+		}
+		if len(chain.Nodes) == 0 {
+			problems.Add("Chain %d in roles has no nodes configured", id)
+		}
+		for _, hs := range chain.HeaderSync {
+			if hs == nil || !hs.Enabled {
+				continue
+			}
+			problems = append(problems, hs.Validate()...)
+		}
+	}
+	return
+}
+
+// Validate checks the header sync direction config
+func (c *HeaderSyncConfig) Validate() (problems Problems) {
+	if c.ListenerConfig == nil || c.Submitter == nil {
+		problems.Add("Header sync of chain %d is not initialized", c.ChainId)
+		return
+	}
+	name := base.GetChainName(c.ChainId) + " -> " + base.GetChainName(c.Submitter.ChainId)
+	if len(c.Nodes) == 0 {
+		problems.Add("Header sync %s: no listener nodes", name)
+	}
+	if len(c.Submitter.Nodes) == 0 {
+		problems.Add("Header sync %s: no submitter nodes", name)
+	}
+	if c.Submitter.HSContract == "" {
+		// No light client contract is built in, the registry overrides provide the defaults of a network
+		field, chain := "Contract", c.Submitter.ChainId
+		if chain == base.TOP {
+			field, chain = "TopContract", c.ChainId
+		}
+		problems.Add(
+			"Header sync %s: no light client contract configured, set the submitter HSContract or the registry %s of chain %d for the %s network",
+			name, field, chain, base.Env(),
+		)
+	} else if !ValidAddress(c.Submitter.HSContract) {
+		problems.Add("Header sync %s: invalid light client contract address %q", name, c.Submitter.HSContract)
+	}
+	if c.Batch < 0 || c.Buffer < 0 || c.Timeout < 0 {
+		problems.Add("Header sync %s: negative batch, buffer or timeout", name)
+	}
+	if c.PauseCheck < 0 {
+		problems.Add("Header sync %s: negative pause check interval", name)
+	}
+	if c.Schedule && c.Submitter.ChainId == base.TOP {
+		problems.Add("Header sync %s: scheduled submission is not supported by the light client on TOP", name)
+	}
+	if c.Submitter.Wallet == nil {
+		problems.Add("Header sync %s: no submitter wallet", name)
+	} else {
+		if len(c.Submitter.Wallet.KeyStoreProviders) == 0 && c.Submitter.Wallet.Path == "" {
+			problems.Add("Header sync %s: no keystore in submitter wallet", name)
+		}
+		if len(c.Submitter.Wallet.Nodes) == 0 {
+			problems.Add("Header sync %s: no submitter wallet nodes", name)
+		}
+	}
+	for _, v := range []struct {
+		name  string
+		value string
+	}{
+		{"MinBalance", c.Submitter.MinBalance},
+		{"BalanceWarn", c.Submitter.BalanceWarn},
+		{"BalanceCritical", c.Submitter.BalanceCritical},
+	} {
+		if v.value == "" {
+			continue
+		}
+		if value, ok := new(big.Int).SetString(v.value, 10); !ok || value.Sign() < 0 {
+			problems.Add("Header sync %s: invalid %s %q", name, v.name, v.value)
+		}
+	}
+	return
+}
+
+// ValidAddress checks if the value is a non-zero hex address
+func ValidAddress(value string) bool {
+	if !common.IsHexAddress(value) || !strings.HasPrefix(strings.ToLower(value), "0x") {
+		return false
+	}
+	return common.HexToAddress(value) != common.Address{}
+}
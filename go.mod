@@ -3,6 +3,7 @@ module github.com/top/top-relayer
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.2.0
 	github.com/btcsuite/btcd v0.21.0-beta
 	github.com/ethereum/go-ethereum v1.10.11
 	github.com/joeqian10/neo-gogogo v1.4.0
@@ -13,5 +14,7 @@ require (
 	github.com/polynetwork/bridge-common v0.0.54-v2
 	github.com/polynetwork/poly v1.7.3-0.20210804073726-5d4f4d4a9371
 	github.com/polynetwork/poly-go-sdk v0.0.0-20210114035303-84e1615f4ad4
+	github.com/syndtr/goleveldb v1.0.1-0.20210819022825-2ae1ddf74ef7
 	github.com/urfave/cli/v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
 	"sync"
 	"syscall"
 
 	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
 	"github.com/top/top-relayer/relayer"
 	"github.com/urfave/cli/v2"
@@ -22,13 +25,17 @@ func main() {
 			&cli.StringFlag{
 				Name:  "config",
 				Value: "config.json",
-				Usage: "configuration file",
+				Usage: "configuration file, json, yaml or toml",
 			},
 			&cli.StringFlag{
 				Name:  "roles",
 				Value: "roles.json",
 				Usage: "roles configuration file",
 			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "network profile: mainnet, testnet, devnet or local, overrides the config env",
+			},
 			&cli.StringFlag{
 				Name:  "wallet",
 				Value: "",
@@ -39,34 +46,45 @@ func main() {
 		Commands: []*cli.Command{
 			&cli.Command{
 				Name:   relayer.CHECK_WALLET,
-				Usage:  "Check wallet status",
+				Usage:  "Check balances, nonces and pending txs of submitter wallets",
 				Action: command(relayer.CHECK_WALLET),
 				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:  "chain",
+						Usage: "only check submitters to the chain",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "print status in json",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   relayer.SET_HEADER_HEIGHT,
+				Usage:  "Force header sync to resume after the height, running relayer picks it up",
+				Action: command(relayer.SET_HEADER_HEIGHT),
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:     "height",
+						Usage:    "last synced header height to resume after",
+						Required: true,
+					},
 					&cli.Int64Flag{
 						Name:     "chain",
-						Usage:    "target side chain",
+						Usage:    "header source chain",
 						Required: true,
 					},
+					&cli.Int64Flag{
+						Name:  "target",
+						Usage: "header target chain, default to TOP",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-rewind",
+						Usage: "allow height below the light client height",
+					},
 				},
 			},
 			// &cli.Command{
-			// 	Name:   relayer.SET_HEADER_HEIGHT,
-			// 	Usage:  "Set side chain header sync height",
-			// 	Action: command(relayer.SET_HEADER_HEIGHT),
-			// 	Flags: []cli.Flag{
-			// 		&cli.Int64Flag{
-			// 			Name:     "height",
-			// 			Usage:    "target block height",
-			// 			Required: true,
-			// 		},
-			// 		&cli.Int64Flag{
-			// 			Name:     "chain",
-			// 			Usage:    "target side chain",
-			// 			Required: true,
-			// 		},
-			// 	},
-			// },
-			// &cli.Command{
 			// 	Name:   relayer.SET_TX_HEIGHT,
 			// 	Usage:  "Set side chain tx sync height",
 			// 	Action: command(relayer.SET_TX_HEIGHT),
@@ -83,11 +101,65 @@ func main() {
 			// 		},
 			// 	},
 			// },
-			// &cli.Command{
-			// 	Name:   relayer.STATUS,
-			// 	Usage:  "Check side chain header/tx sync height",
-			// 	Action: command(relayer.STATUS),
-			// },
+			&cli.Command{
+				Name:   relayer.VALIDATE,
+				Usage:  "Validate config and roles, decrypt wallets and probe node chain ids",
+				Action: validate,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "offline",
+						Usage: "skip wallet decryption and node probes",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   relayer.INIT_GENESIS,
+				Usage:  "Initialize the light client on TOP with the genesis header of the chain",
+				Action: command(relayer.INIT_GENESIS),
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:     "chain",
+						Usage:    "header source chain",
+						Required: true,
+					},
+					&cli.Int64Flag{
+						Name:  "height",
+						Usage: "genesis header height, default to the latest confirmed epoch header",
+					},
+					&cli.StringFlag{
+						Name:  "emitter",
+						Usage: "emitter contract of the source chain",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   relayer.DEPOSIT,
+				Usage:  "Deposit the bond required by the light client contract for relayer accounts",
+				Action: command(relayer.DEPOSIT),
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:     "chain",
+						Usage:    "chain of the light client contract",
+						Required: true,
+					},
+				},
+			},
+			&cli.Command{
+				Name:   relayer.STATUS,
+				Usage:  "Check header sync status of every direction",
+				Action: command(relayer.STATUS),
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "print status in json",
+					},
+					&cli.Int64Flag{
+						Name:  "interval",
+						Usage: "sampling interval in seconds to estimate catch up time, 0 to skip",
+						Value: 10,
+					},
+				},
+			},
 			// &cli.Command{
 			// 	Name:   relayer.RELAY_TX,
 			// 	Usage:  "Submit cross chain tx",
@@ -252,26 +324,97 @@ func start(c *cli.Context) error {
 		log.Error("Failed to initialize configuration", "err", err)
 		os.Exit(2)
 	}
+	if problems := relayer.Validate(config, false); len(problems) > 0 {
+		log.Error("Invalid configuration", "err", problems)
+		os.Exit(2)
+	}
 
 	wg := &sync.WaitGroup{}
 	ctx, cancel := context.WithCancel(context.Background())
 	status := 0
-	err = relayer.Start(ctx, wg, config)
+	server, err := relayer.Start(ctx, wg, config)
 	if err == nil {
 		sc := make(chan os.Signal, 10)
 		signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGSTOP, syscall.SIGQUIT)
-		sig := <-sc
-		log.Info("Poly relayer is exiting with received signal", "signal", sig.String())
+		for sig := range sc {
+			if sig == syscall.SIGHUP {
+				log.Info("Reloading config and roles with received signal", "signal", sig.String())
+				err = reload(c, server)
+				if err != nil {
+					log.Error("Failed to reload config", "err", err)
+				}
+				continue
+			}
+			log.Info("Poly relayer is exiting with received signal", "signal", sig.String())
+			break
+		}
 	} else {
 		log.Error("Failed to start relayer service", "err", err)
 		status = 2
 	}
+	server.Stop()
 	cancel()
 	wg.Wait()
 	os.Exit(status)
 	return nil
 }
 
+// Re-read the config and roles files and apply them to the running server
+func reload(c *cli.Context, server *relayer.Server) (err error) {
+	conf, err := config.New(c.String("config"))
+	if err != nil {
+		return
+	}
+	if conf.Network() != base.Env() {
+		return fmt.Errorf("Network can not be changed from %s to %s without restart", base.Env(), conf.Network())
+	}
+	// Chain registry is global, running roles would observe the changes before the reload is applied
+	if config.CONFIG != nil && !reflect.DeepEqual(conf.Registry, config.CONFIG.Registry) {
+		return fmt.Errorf("Chain registry can not be changed without restart")
+	}
+	err = conf.ReadRoles(c.String("roles"))
+	if err != nil {
+		return
+	}
+	// The new config is validated before it becomes current, the chain registry is kept as the network is unchanged
+	err = conf.Prepare()
+	if err != nil {
+		return
+	}
+	if problems := relayer.Validate(conf, false); len(problems) > 0 {
+		return problems
+	}
+	err = server.Reload(conf)
+	// Directions failed to restart are dropped by the server, the new config is applied anyway
+	config.CONFIG = conf
+	return
+}
+
+// Validate config and roles files, all problems found are printed
+func validate(c *cli.Context) error {
+	conf, problems, err := config.Load(c.String("config"))
+	if err != nil {
+		log.Error("Failed to parse config file", "err", err)
+		os.Exit(2)
+	}
+	err = conf.ReadRoles(c.String("roles"))
+	if err != nil {
+		problems.Add("%v", err)
+	}
+	err = conf.Init()
+	if err != nil {
+		problems.Add("%v", err)
+	} else {
+		problems = append(problems, relayer.Validate(conf, !c.Bool("offline"))...)
+	}
+	if len(problems) > 0 {
+		fmt.Println(problems.Error())
+		os.Exit(2)
+	}
+	fmt.Println("Config is valid")
+	return nil
+}
+
 func command(method string) func(*cli.Context) error {
 	return func(c *cli.Context) error {
 		config, err := config.New(c.String("config"))
@@ -298,6 +441,7 @@ func Init(ctx *cli.Context) (err error) {
 	// Set wallet path
 	config.WALLET_PATH = ctx.String("wallet")
 	config.CONFIG_PATH = ctx.String("config")
+	config.NETWORK = ctx.String("network")
 
 	log.Init()
 	return
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"strconv"
+
+	ethmetrics "github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/top/top-relayer/msg"
+)
+
+// Registry holds all the relayer metrics
+var Registry = ethmetrics.NewRegistry()
+
+func init() {
+	// Metrics constructors return nil metrics unless enabled
+	ethmetrics.Enabled = true
+}
+
+// WalletBalance of the submitter account in gwei
+func WalletBalance(chain uint64, address string) ethmetrics.Gauge {
+	return ethmetrics.GetOrRegisterGauge(key("wallet_balance_gwei", "chain", strconv.FormatUint(chain, 10), "address", address), Registry)
+}
+
+// Header sync metrics of a single direction, labeled with the source and target chain ids
+type HeaderSync struct {
+	labels []string
+
+	SourceTip     ethmetrics.Gauge // source chain latest height
+	Synced        ethmetrics.Gauge // light client height on target chain
+	Fetched       ethmetrics.Gauge // last fetched header height
+	Buffer        ethmetrics.Gauge // headers buffered in channel
+	Submitted     ethmetrics.Counter
+	Skipped       ethmetrics.Counter // headers skipped by scheduled submission
+	Rollbacks     ethmetrics.Counter
+	ConfirmChecks ethmetrics.Counter   // confirm check failures
+	Latency       ethmetrics.Histogram // header submit latency in milliseconds
+	Reorgs        ethmetrics.Counter
+	ReorgDepth    ethmetrics.Gauge // depth of the last reorg
+	Paused        ethmetrics.Gauge // pause flags of the light client contract
+}
+
+// ForHeaderSync returns the metrics of header sync from chain to target
+func ForHeaderSync(chain, target uint64) *HeaderSync {
+	m := &HeaderSync{labels: []string{"source", strconv.FormatUint(chain, 10), "target", strconv.FormatUint(target, 10)}}
+	m.SourceTip = m.gauge("source_tip")
+	m.Synced = m.gauge("light_client_height")
+	m.Fetched = m.gauge("fetch_height")
+	m.Buffer = m.gauge("buffer")
+	m.Submitted = m.counter("headers_submitted")
+	m.Skipped = m.counter("headers_skipped")
+	m.Rollbacks = m.counter("rollbacks")
+	m.ConfirmChecks = m.counter("confirm_check_failures")
+	m.Reorgs = m.counter("reorgs")
+	m.ReorgDepth = m.gauge("reorg_depth")
+	m.Paused = m.gauge("contract_paused")
+	m.Latency = ethmetrics.GetOrRegisterHistogram(m.key("submit_latency_ms"), Registry, ethmetrics.NewExpDecaySample(1028, 0.015))
+	return m
+}
+
+func (m *HeaderSync) key(name string, labels ...string) string {
+	return key("header_sync_"+name, append(append([]string{}, m.labels...), labels...)...)
+}
+
+func (m *HeaderSync) gauge(name string) ethmetrics.Gauge {
+	return ethmetrics.GetOrRegisterGauge(m.key(name), Registry)
+}
+
+func (m *HeaderSync) counter(name string) ethmetrics.Counter {
+	return ethmetrics.GetOrRegisterCounter(m.key(name), Registry)
+}
+
+// SubmitFailure counts the submit failure by error type
+func (m *HeaderSync) SubmitFailure(err error) {
+	ethmetrics.GetOrRegisterCounter(m.key("submit_failures", "type", ErrorType(err)), Registry).Inc(1)
+}
+
+func ErrorType(err error) string {
+	switch err {
+	case msg.ERR_HEADER_INCONSISTENT:
+		return "header_inconsistent"
+	case msg.ERR_HEADER_MISSING:
+		return "header_missing"
+	case msg.ERR_HEADER_SUBMIT_FAILURE:
+		return "header_submit_failure"
+	case msg.ERR_LOW_BALANCE:
+		return "low_balance"
+	case msg.ERR_HEADER_EXISTS:
+		return "header_exists"
+	case msg.ERR_CONTRACT_PAUSED:
+		return "contract_paused"
+	case msg.ERR_CONTRACT_UNINITIALIZED:
+		return "contract_uninitialized"
+	case msg.ERR_CONTRACT_PANIC:
+		return "contract_panic"
+	case msg.ERR_TX_EXEC_FAILURE:
+		return "tx_exec_failure"
+	case msg.ERR_TX_TIMEOUT:
+		return "tx_timeout"
+	case msg.ERR_TX_CANCELLED:
+		return "tx_cancelled"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	ethmetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// key composes the registry key of the metric with the label pairs, e.g. name{source="1",target="0"}
+func key(name string, labels ...string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// split the registry key into the metric name and the label pairs
+func split(key string) (name, labels string) {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		return key[:i], strings.TrimSuffix(key[i+1:], "}")
+	}
+	return key, ""
+}
+
+func series(name, labels string, extra ...string) string {
+	if len(extra) > 0 {
+		pair := key("", extra...)
+		pair = pair[1 : len(pair)-1]
+		if labels == "" {
+			labels = pair
+		} else {
+			labels += "," + pair
+		}
+	}
+	if labels == "" {
+		return name
+	}
+	return name + "{" + labels + "}"
+}
+
+// Handler serves the metrics in prometheus text format, series of the same metric share one type line
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var keys []string
+		Registry.Each(func(key string, _ interface{}) {
+			keys = append(keys, key)
+		})
+		sort.Strings(keys)
+
+		buf := new(bytes.Buffer)
+		typed := map[string]bool{}
+		for _, key := range keys {
+			name, labels := split(key)
+			kind, lines := "", []string{}
+			switch m := Registry.Get(key).(type) {
+			case ethmetrics.Counter:
+				kind = "counter"
+				lines = append(lines, fmt.Sprintf("%s %d", series(name, labels), m.Count()))
+			case ethmetrics.Gauge:
+				kind = "gauge"
+				lines = append(lines, fmt.Sprintf("%s %d", series(name, labels), m.Value()))
+			case ethmetrics.Histogram:
+				kind = "summary"
+				s := m.Snapshot()
+				for i, v := range s.Percentiles(quantiles) {
+					q := strconv.FormatFloat(quantiles[i], 'f', -1, 64)
+					lines = append(lines, fmt.Sprintf("%s %v", series(name, labels, "quantile", q), v))
+				}
+				lines = append(lines,
+					fmt.Sprintf("%s %d", series(name+"_sum", labels), s.Sum()),
+					fmt.Sprintf("%s %d", series(name+"_count", labels), s.Count()),
+				)
+			default:
+				continue
+			}
+			if !typed[name] {
+				typed[name] = true
+				fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+			}
+			for _, line := range lines {
+				buf.WriteString(line)
+				buf.WriteByte('\n')
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerLabels(t *testing.T) {
+	for _, direction := range [][2]uint64{{1, 0}, {0, 1}} {
+		m := ForHeaderSync(direction[0], direction[1])
+		m.Submitted.Inc(2)
+		m.SubmitFailure(nil)
+		m.Latency.Update(100)
+	}
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`header_sync_headers_submitted{source="1",target="0"} 2`,
+		`header_sync_headers_submitted{source="0",target="1"} 2`,
+		`header_sync_submit_failures{source="1",target="0",type="other"} 1`,
+		`header_sync_submit_latency_ms{source="0",target="1",quantile="0.5"} 100`,
+		`header_sync_submit_latency_ms_count{source="0",target="1"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing series %s", line)
+		}
+	}
+	if n := strings.Count(body, "# TYPE header_sync_headers_submitted counter\n"); n != 1 {
+		t.Errorf("expect one type line per metric, got %d", n)
+	}
+}
@@ -13,4 +13,34 @@ var (
 	ERR_HEADER_SUBMIT_FAILURE = errors.New("Header submit failure")
 	ERR_TX_EXEC_ALWAYS_FAIL   = errors.New("Tx exec always fail")
 	ERR_LOW_BALANCE           = errors.New("Insufficient balance")
+	ERR_TX_TIMEOUT            = errors.New("Tx confirmation timeout")
+	ERR_TX_CANCELLED          = errors.New("Tx cancelled")
+
+	ERR_HEADER_EXISTS          = errors.New("Header already exists")
+	ERR_CONTRACT_PAUSED        = errors.New("Contract paused")
+	ERR_CONTRACT_UNINITIALIZED = errors.New("Contract not initialized")
+	ERR_CONTRACT_PANIC         = errors.New("Contract panic")
 )
+
+// Header submit policy of the errors
+type Policy int
+
+const (
+	POLICY_RETRY    Policy = iota // Transient failure, retry the submission
+	POLICY_SKIP                   // Header accepted already, continue with the next one
+	POLICY_ROLLBACK               // Rollback header sync to the common ancestor
+	POLICY_GIVE_UP                // Stop the header sync and retry after a backoff
+)
+
+func SubmitPolicy(err error) Policy {
+	switch err {
+	case ERR_HEADER_EXISTS:
+		return POLICY_SKIP
+	case ERR_HEADER_INCONSISTENT, ERR_HEADER_MISSING, ERR_HEADER_SUBMIT_FAILURE, ERR_TX_CANCELLED:
+		return POLICY_ROLLBACK
+	case ERR_CONTRACT_PAUSED, ERR_CONTRACT_UNINITIALIZED, ERR_CONTRACT_PANIC:
+		return POLICY_GIVE_UP
+	default:
+		return POLICY_RETRY
+	}
+}
@@ -39,7 +39,22 @@ const (
 type Header struct {
 	Height uint64
 	Hash   []byte
+	Parent []byte
 	Data   []byte
+	Time   uint64 // block timestamp, zero if unknown
+}
+
+// Header sync reset request, the sync will restart from the height
+type Reset struct {
+	Height uint64
+	Err    error
+	Force  bool // Skip searching for common ancestor
+}
+
+// Block hash at height, hex encoded
+type BlockHash struct {
+	Height uint64
+	Hash   string
 }
 
 type PolyComposer func(*Tx) error
@@ -1,13 +1,25 @@
 package relayer
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethcommon "github.com/polynetwork/bridge-common/chains/eth"
 	"github.com/polynetwork/bridge-common/log"
+	"github.com/polynetwork/bridge-common/util"
 	"github.com/urfave/cli/v2"
 
 	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/relayer/sender"
+	"github.com/top/top-relayer/store"
 )
 
 const (
@@ -21,31 +33,97 @@ const (
 	CHECK_SKIP        = "checkskip"
 	CREATE_ACCOUNT    = "createaccount"
 	CHECK_WALLET      = "wallet"
+	VALIDATE          = "validate"
+	INIT_GENESIS      = "init-genesis"
+	DEPOSIT           = "deposit"
 )
 
 var _Handlers = map[string]func(*cli.Context) error{}
 
 func init() {
-	// _Handlers[SET_HEADER_HEIGHT] = SetHeaderSyncHeight
-	// _Handlers[STATUS] = Status
+	_Handlers[SET_HEADER_HEIGHT] = SetHeaderSyncHeight
+	_Handlers[STATUS] = Status
 	// _Handlers[HTTP] = Http
 	// _Handlers[PATCH] = Patch
 	// _Handlers[SKIP] = Skip
 	// _Handlers[CHECK_SKIP] = CheckSkip
 	_Handlers[CHECK_WALLET] = CheckWallet
 	_Handlers[CREATE_ACCOUNT] = CreateAccount
+	_Handlers[INIT_GENESIS] = InitGenesis
+	_Handlers[DEPOSIT] = Deposit
+}
+
+// Wallet status of a header sync submitter
+type WalletStatus struct {
+	Chain    uint64
+	Target   uint64
+	Accounts []sender.Status
+	Error    string `json:",omitempty"`
+}
+
+// Enabled header syncs submitting to the target chain, all targets if target is nil
+func walletSyncs(c *config.Config, target *uint64) (list []*config.HeaderSyncConfig) {
+	ids := []uint64{}
+	for id := range c.Chains {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		for _, hs := range c.Chains[id].HeaderSync {
+			if hs == nil || !hs.Enabled || hs.ListenerConfig == nil || hs.Submitter == nil {
+				continue
+			}
+			if target != nil && hs.Submitter.ChainId != *target {
+				continue
+			}
+			list = append(list, hs)
+		}
+	}
+	return
 }
 
 func CheckWallet(ctx *cli.Context) (err error) {
-	chain := uint64(ctx.Int("chain"))
-	for _, c := range base.CHAINS {
-		if chain > 0 && c != chain {
+	var target *uint64
+	if ctx.IsSet("chain") {
+		chain := uint64(ctx.Int("chain"))
+		target = &chain
+	}
+
+	list := []*WalletStatus{}
+	for _, hs := range walletSyncs(config.CONFIG, target) {
+		status := &WalletStatus{Chain: hs.ChainId, Target: hs.Submitter.ChainId}
+		list = append(list, status)
+		submitter := GetSubmitter(hs.Submitter.ChainId)
+		if submitter == nil {
+			status.Error = fmt.Sprintf("No submitter for chain %d available", hs.Submitter.ChainId)
 			continue
 		}
-		fmt.Printf("Wallet status %s:\n", base.GetChainName(chain))
-		// _, err := GetSubmitter(chain)
+		err = submitter.Init(hs)
+		if err == nil {
+			status.Accounts, err = submitter.Wallets()
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
 	}
-	return nil
+	if ctx.Bool("json") {
+		fmt.Println(util.Verbose(list))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DIRECTION\tADDRESS\tBALANCE\tNONCE\tPENDING\tERROR")
+	for _, s := range list {
+		direction := fmt.Sprintf("%s -> %s", base.GetChainName(s.Chain), base.GetChainName(s.Target))
+		if s.Error != "" || len(s.Accounts) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", direction, s.Error)
+		}
+		for _, a := range s.Accounts {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t\n", direction, a.Address, a.Balance, a.Nonce, a.Pending)
+		}
+	}
+	return w.Flush()
 }
 
 // func RelayTx(ctx *cli.Context) (err error) {
@@ -209,55 +287,194 @@ func CheckWallet(ctx *cli.Context) (err error) {
 // 	return bus.NewRedisSortedTxBus(h.redis, chain, ty).Len(context.Background())
 // }
 
-// func Status(ctx *cli.Context) (err error) {
-// 	h := NewStatusHandler(config.CONFIG.Bus.Redis)
-// 	for _, chain := range base.CHAINS {
-// 		fmt.Printf("Status %s:\n", base.GetChainName(chain))
-
-// 		latest, _ := h.Height(chain, bus.KEY_HEIGHT_CHAIN)
-// 		sync, _ := h.Height(chain, bus.KEY_HEIGHT_CHAIN_HEADER)
-// 		mark, _ := h.Height(chain, bus.KEY_HEIGHT_HEADER)
-// 		tx, _ := h.Height(chain, bus.KEY_HEIGHT_TX)
-// 		header := uint64(0)
-// 		switch chain {
-// 		case base.BSC, base.HECO, base.MATIC, base.ETH, base.O3:
-// 			header, _ = h.poly.Node().GetSideChainHeight(chain)
-// 		default:
-// 		}
+// Find the header sync config of the direction
+func headerSyncConfig(chain, target uint64) (*config.HeaderSyncConfig, error) {
+	for _, c := range config.CONFIG.Chains {
+		for _, conf := range c.HeaderSync {
+			if conf != nil && conf.ListenerConfig != nil && conf.Submitter != nil && conf.ChainId == chain && conf.Submitter.ChainId == target {
+				return conf, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("No header sync from chain %d to %d configured", chain, target)
+}
 
-// 		fmt.Printf("  Latest node height: %v\n", latest)
-// 		fmt.Printf("  Latest sync height: %v\n", header)
-// 		fmt.Printf("  Header sync height: %v\n", sync)
-// 		fmt.Printf("  Header mark height: %v\n", mark)
-// 		fmt.Printf("  tx listen height  : %v\n", tx)
-// 		if latest > 0 {
-// 			headerDiff := int64(latest) - int64(header)
-// 			if headerDiff < 0 {
-// 				headerDiff = 0
-// 			}
-// 			txDiff := int64(latest) - int64(tx)
-// 			if txDiff < 0 {
-// 				txDiff = 0
-// 			}
-// 			fmt.Printf("  header sync height diff: %v\n", headerDiff)
-// 			fmt.Printf("  tx listen height diff  : %v\n", txDiff)
-// 		}
-// 		qSrc, _ := h.LenSorted(chain, msg.SRC)
-// 		qPoly, _ := h.Len(chain, msg.POLY)
-// 		fmt.Printf("  src tx queue size : %v\n", qSrc)
-// 		fmt.Printf("  poly tx queue size: %v\n", qPoly)
-// 	}
-// 	qDelayed, _ := h.LenDelayed()
-// 	fmt.Printf("Status shared:\n")
-// 	fmt.Printf("  delayed tx queue size: %v\n", qDelayed)
-// 	return nil
-// }
+// InitGenesis bootstraps the light client on TOP with the source chain header at the height,
+// or the latest confirmed header, aligned to epoch for chains rotating validator set with epoch headers.
+func InitGenesis(ctx *cli.Context) (err error) {
+	chain := uint64(ctx.Int64("chain"))
+	if chain == base.TOP {
+		return fmt.Errorf("Genesis of chain %s is not supported", base.GetChainName(chain))
+	}
+	hs, err := headerSyncConfig(chain, base.TOP)
+	if err != nil {
+		return
+	}
+	submitter := GetSubmitter(base.TOP)
+	if submitter == nil {
+		return fmt.Errorf("No submitter for chain %d available", base.TOP)
+	}
+	initializer, ok := submitter.(IGenesisInitializer)
+	if !ok {
+		return fmt.Errorf("Submitter of chain %s does not support genesis", base.GetChainName(base.TOP))
+	}
+	err = submitter.Init(hs)
+	if err != nil {
+		return
+	}
+	listener := GetListener(chain)
+	if listener == nil {
+		return fmt.Errorf("No listener for chain %d available", chain)
+	}
+	err = listener.Init(hs, submitter.SDK())
+	if err != nil {
+		return
+	}
+	return initGenesis(chain, uint64(ctx.Int64("height")), ctx.String("emitter"), listener, submitter, initializer)
+}
 
-// func SetHeaderSyncHeight(ctx *cli.Context) (err error) {
-// 	height := uint64(ctx.Int("height"))
-// 	chain := uint64(ctx.Int("chain"))
-// 	return NewStatusHandler(config.CONFIG.Bus.Redis).SetHeight(chain, bus.KEY_HEIGHT_HEADER_RESET, height)
-// }
+// Submit the genesis header of the chain at height, the latest confirmed epoch header if height is zero
+func initGenesis(
+	chain, height uint64, emitter string, listener IChainListener, submitter IChainSubmitter, initializer IGenesisInitializer,
+) (err error) {
+	current, err := submitter.GetSideChainHeight(chain)
+	if err != nil {
+		return fmt.Errorf("Failed to get light client height %v", err)
+	}
+	if current > 0 {
+		return fmt.Errorf("Light client of chain %s is already initialized at height %d", base.GetChainName(chain), current)
+	}
+
+	epoch := base.Epoch(chain)
+	if height == 0 {
+		latest, err := listener.LatestHeight()
+		if err != nil {
+			return err
+		}
+		confirms := uint64(listener.Defer())
+		if latest <= confirms {
+			return fmt.Errorf("No confirmed header on chain %s yet, latest height %d", base.GetChainName(chain), latest)
+		}
+		height = latest - confirms
+		if epoch > 0 {
+			height -= height % epoch
+		}
+	} else if epoch > 0 && height%epoch != 0 {
+		log.Warn("Genesis height is not an epoch header", "chain", chain, "height", height, "epoch", epoch)
+	}
+
+	header, err := listener.Header(height)
+	if err != nil {
+		return
+	}
+	hash, err := initializer.InitGenesis(context.Background(), header, emitter)
+	if err != nil {
+		return fmt.Errorf("Genesis tx %s failed %v", hash, err)
+	}
+
+	// Verify the genesis took effect
+	current, err = submitter.GetSideChainHeight(chain)
+	if err != nil {
+		return fmt.Errorf("Failed to verify light client height %v", err)
+	}
+	if current != height {
+		return fmt.Errorf("Light client height %d does not match genesis height %d", current, height)
+	}
+	stored, err := submitter.GetSideChainHeader(chain, height)
+	if err != nil {
+		return fmt.Errorf("Failed to verify light client header hash %v", err)
+	}
+	if !bytes.Equal(stored, header.Hash) {
+		return fmt.Errorf("Light client header hash %x does not match genesis hash %x", stored, header.Hash)
+	}
+	log.Info("Initialized light client genesis", "chain", chain, "height", height, "hash", hex.EncodeToString(header.Hash), "tx", hash)
+	return
+}
+
+// Deposit the bond required by the light client contract on the chain for the relayer accounts submitting TOP headers
+func Deposit(ctx *cli.Context) (err error) {
+	chain := uint64(ctx.Int64("chain"))
+	hs, err := headerSyncConfig(base.TOP, chain)
+	if err != nil {
+		return
+	}
+	submitter := GetSubmitter(chain)
+	if submitter == nil {
+		return fmt.Errorf("No submitter for chain %d available", chain)
+	}
+	bonder, ok := submitter.(IBridgeBonder)
+	if !ok {
+		return fmt.Errorf("Submitter of chain %s does not require deposit", base.GetChainName(chain))
+	}
+	err = submitter.Init(hs)
+	if err != nil {
+		return
+	}
+	err = bonder.Deposit(context.Background())
+	if err != nil {
+		return
+	}
+	log.Info("Relayer accounts bonded", "chain", chain, "contract", hs.Submitter.HSContract)
+	return
+}
+
+// SetHeaderSyncHeight forces the header sync of the direction to resume after the height,
+// the running relayer picks it up from the state store path.
+func SetHeaderSyncHeight(ctx *cli.Context) (err error) {
+	height := uint64(ctx.Int64("height"))
+	chain := uint64(ctx.Int64("chain"))
+	target := base.TOP
+	if ctx.IsSet("target") {
+		target = uint64(ctx.Int64("target"))
+	} else if chain == base.TOP {
+		return fmt.Errorf("Target chain is required for header sync from %s", base.GetChainName(chain))
+	}
+	if height == 0 {
+		return fmt.Errorf("Invalid header sync height")
+	}
+
+	hs, err := headerSyncConfig(chain, target)
+	if err != nil {
+		return
+	}
+	listener := GetListener(hs.ChainId)
+	if listener == nil {
+		return fmt.Errorf("No listener for chain %d available", hs.ChainId)
+	}
+	peer, err := ethcommon.WithOptions(hs.Submitter.ChainId, hs.Submitter.Nodes, time.Minute, 1)
+	if err != nil {
+		return
+	}
+	err = listener.Init(hs, peer)
+	if err != nil {
+		return
+	}
+	tip, err := listener.LastHeaderSync(0, 0)
+	if err != nil {
+		return fmt.Errorf("Failed to get light client height %v", err)
+	}
+	err = checkForceHeight(height, tip, ctx.Bool("allow-rewind"))
+	if err != nil {
+		return
+	}
+	err = store.SetForceHeight(config.CONFIG.StorePath, chain, target, height)
+	if err != nil {
+		return
+	}
+	log.Info("Forced header sync height", "chain", chain, "target", target, "height", height, "light_client", tip)
+	return
+}
+
+// Forced height must not skip headers above the light client tip, or rewind below it unless allowed
+func checkForceHeight(height, tip uint64, rewind bool) error {
+	if height > tip {
+		return fmt.Errorf("Height %d is above the light client height %d, headers in between would be skipped", height, tip)
+	}
+	if height < tip && !rewind {
+		return fmt.Errorf("Height %d is below the light client height %d, use --allow-rewind to resubmit %d headers", height, tip, tip-height)
+	}
+	return nil
+}
 
 // func SetTxSyncHeight(ctx *cli.Context) (err error) {
 // 	height := uint64(ctx.Int("height"))
@@ -279,6 +496,33 @@ func CheckWallet(ctx *cli.Context) (err error) {
 // 	return
 // }
 
+func Status(ctx *cli.Context) (err error) {
+	list, err := NewSyncStatus(config.CONFIG)
+	if err != nil {
+		return
+	}
+	SampleSyncStatus(list, time.Duration(ctx.Int("interval"))*time.Second)
+	if ctx.Bool("json") {
+		fmt.Println(util.Verbose(list))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAIN\tDIRECTION\tSOURCE TIP\tSYNCED\tLAG\tCATCH UP\tSTATE\tERROR")
+	for _, s := range list {
+		catchUp := "-"
+		if s.CatchUp > 0 {
+			catchUp = (time.Duration(s.CatchUp) * time.Second).String()
+		}
+		state := "active"
+		if s.Suspended() {
+			state = fmt.Sprintf("suspended (paused %d)", s.Flags)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n", s.Chain, s.Direction(), s.Tip, s.Synced, s.Lag, catchUp, state, s.Error)
+	}
+	return w.Flush()
+}
+
 func HandleCommand(method string, ctx *cli.Context) error {
 	h, ok := _Handlers[method]
 	if !ok {
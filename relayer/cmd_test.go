@@ -0,0 +1,141 @@
+package relayer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/store"
+)
+
+func TestWalletSyncs(t *testing.T) {
+	sync := func(chain, target uint64, enabled bool) *config.HeaderSyncConfig {
+		return &config.HeaderSyncConfig{
+			Enabled: enabled, ListenerConfig: &config.ListenerConfig{ChainId: chain},
+			Submitter: &config.SubmitterConfig{ChainId: target},
+		}
+	}
+	c := &config.Config{Chains: map[uint64]*config.ChainConfig{
+		base.ETH: {HeaderSync: [2]*config.HeaderSyncConfig{sync(base.ETH, base.TOP, true), sync(base.TOP, base.ETH, false)}},
+		base.BSC: {HeaderSync: [2]*config.HeaderSyncConfig{sync(base.BSC, base.TOP, true), sync(base.TOP, base.BSC, true)}},
+	}}
+
+	list := walletSyncs(c, nil)
+	if len(list) != 3 || list[0].ChainId != base.ETH || list[1].ChainId != base.BSC || list[2].Submitter.ChainId != base.BSC {
+		t.Fatalf("expect the enabled header syncs in chain order, got %d", len(list))
+	}
+	target := base.ETH
+	if list = walletSyncs(c, &target); len(list) != 0 {
+		t.Fatalf("expect disabled direction to eth skipped, got %d", len(list))
+	}
+	target = base.TOP
+	if list = walletSyncs(c, &target); len(list) != 2 {
+		t.Fatalf("expect 2 header syncs to top, got %d", len(list))
+	}
+}
+
+func TestCheckForceHeight(t *testing.T) {
+	cases := []struct {
+		height uint64
+		rewind bool
+		ok     bool
+	}{
+		{100, false, true},
+		{101, false, false},
+		{101, true, false},
+		{99, false, false},
+		{99, true, true},
+	}
+	for _, c := range cases {
+		if err := checkForceHeight(c.height, 100, c.rewind); (err == nil) != c.ok {
+			t.Fatalf("height %d rewind %v: expect ok %v, got %v", c.height, c.rewind, c.ok, err)
+		}
+	}
+}
+
+func TestApplyForceHeight(t *testing.T) {
+	h := newTestHandler(t, &fakeListener{}, &fakeSubmitter{}, 0)
+	path := filepath.Join(t.TempDir(), "store")
+	db, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h.state = db.State(base.ETH, base.TOP)
+	if err = store.SetForceHeight(path, base.ETH, base.TOP, 100); err != nil {
+		t.Fatal(err)
+	}
+	h.checkForceHeight()
+	if reset := <-h.reset; reset.Height != 101 || !reset.Force {
+		t.Fatalf("expect forced reset to the next height, got %+v", reset)
+	}
+	if height, _ := h.state.ForceHeight(); height != 0 {
+		t.Fatalf("expect applied forced height cleared, got %d", height)
+	}
+}
+
+// Source chain with the latest height and confirmation depth
+type genesisListener struct {
+	*fakeListener
+	latest uint64
+}
+
+func (l *genesisListener) LatestHeight() (uint64, error) { return l.latest, nil }
+
+func (l *genesisListener) Defer() int { return 15 }
+
+// Light client accepting the genesis header, the stored hash is kept as the header hash unless corrupt
+type genesisSubmitter struct {
+	*fakeSubmitter
+	height  uint64
+	genesis *msg.Header
+	corrupt bool
+}
+
+func (s *genesisSubmitter) GetSideChainHeight(chainId uint64) (uint64, error) { return s.height, nil }
+
+func (s *genesisSubmitter) InitGenesis(ctx context.Context, header *msg.Header, emitter string) (hash string, err error) {
+	s.genesis, s.height = header, header.Height
+	s.hashes = map[uint64][]byte{header.Height: header.Hash}
+	if s.corrupt {
+		s.hashes[header.Height] = blockHash(1, 0, header.Height)
+	}
+	return "0x01", nil
+}
+
+func TestInitGenesis(t *testing.T) {
+	cases := []struct {
+		name    string
+		chain   uint64
+		height  uint64
+		current uint64
+		corrupt bool
+		genesis uint64 // zero if rejected
+	}{
+		{"latest confirmed header", base.ETH, 0, 0, false, 985},
+		{"latest epoch header", base.BSC, 0, 0, false, 800},
+		{"chosen header", base.BSC, 900, 0, false, 900},
+		{"initialized already", base.ETH, 0, 100, false, 0},
+		{"hash mismatch", base.ETH, 900, 0, true, 0},
+	}
+	for _, c := range cases {
+		listener := &genesisListener{fakeListener: &fakeListener{hashes: branchHashes(0, 0, 1000)}, latest: 1000}
+		submitter := &genesisSubmitter{fakeSubmitter: &fakeSubmitter{}, height: c.current, corrupt: c.corrupt}
+		err := initGenesis(c.chain, c.height, "", listener, submitter, submitter)
+		if c.genesis == 0 {
+			if err == nil {
+				t.Fatalf("%s: expect genesis rejected", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if submitter.genesis.Height != c.genesis {
+			t.Fatalf("%s: expect genesis at %d, got %d", c.name, c.genesis, submitter.genesis.Height)
+		}
+	}
+}
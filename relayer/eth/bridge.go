@@ -0,0 +1,221 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/abi/bridge"
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/msg"
+)
+
+// Pause flags of the bridge contract
+const (
+	PAUSED_DEPOSIT   = 1
+	PAUSED_WITHDRAW  = 2
+	PAUSED_ADD_BLOCK = 4
+	PAUSED_CHALLENGE = 8
+	PAUSED_VERIFY    = 16
+)
+
+// Default seconds between polls of the bridge pause flags
+const PAUSE_CHECK = 30
+
+// BridgeStatus of the light client contract and the bonds of relayer accounts
+type BridgeStatus struct {
+	Contract    string
+	Initialized bool
+	Paused      uint64
+	LockAmount  *big.Int
+	Bonds       map[string]*big.Int // bonded balance of relayer accounts
+}
+
+// Unbonded relayer accounts which can not add blocks
+func (b *BridgeStatus) Unbonded() (list []string) {
+	for account, bond := range b.Bonds {
+		if bond.Cmp(b.LockAmount) < 0 {
+			list = append(list, account)
+		}
+	}
+	return
+}
+
+func (s *Submitter) BridgeStatus() (status *BridgeStatus, err error) {
+	caller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
+	if err != nil {
+		return
+	}
+	status = &BridgeStatus{Contract: s.hsContract.Hex(), Bonds: map[string]*big.Int{}}
+	status.Initialized, err = caller.Initialized(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Get bridge initialized error %v", err)
+	}
+	paused, err := caller.Paused(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Get bridge paused error %v", err)
+	}
+	status.Paused = paused.Uint64()
+	status.LockAmount, err = caller.LockEthAmount(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Get bridge lock amount error %v", err)
+	}
+	if s.sender == nil {
+		return
+	}
+	for _, account := range s.sender.Accounts() {
+		status.Bonds[account.Hex()], err = caller.BalanceOf(nil, account)
+		if err != nil {
+			return nil, fmt.Errorf("Get bridge balance of %s error %v", account.Hex(), err)
+		}
+	}
+	return
+}
+
+// Ready checks if the bridge accepts blocks, bonds are only checked for the relayer accounts of a configured wallet
+func (b *BridgeStatus) Ready(chain string) (err error) {
+	if !b.Initialized {
+		return fmt.Errorf("%w: bridge %s on %s has no genesis block", msg.ERR_CONTRACT_UNINITIALIZED, b.Contract, chain)
+	}
+	if unbonded := b.Unbonded(); len(unbonded) > 0 {
+		sort.Strings(unbonded)
+		return fmt.Errorf(
+			"Relayer accounts %s are not bonded on bridge %s of %s, lock amount %s, run the deposit command first",
+			strings.Join(unbonded, ","), b.Contract, chain, b.LockAmount,
+		)
+	}
+	return
+}
+
+// Preflight checks if the bridge contract can accept blocks, and the bonds of the relayer accounts if a wallet is configured
+func (s *Submitter) Preflight() (err error) {
+	status, err := s.BridgeStatus()
+	if err != nil {
+		return
+	}
+	if status.Paused&PAUSED_ADD_BLOCK != 0 {
+		// Submission starts suspended and resumes once the bridge is unpaused
+		log.Warn("Bridge paused adding blocks, header submission suspended", "chain", s.name, "contract", status.Contract, "flags", status.Paused)
+	}
+	err = status.Ready(s.name)
+	if err != nil {
+		return
+	}
+	log.Info("Bridge preflight check passed", "chain", s.name, "contract", status.Contract, "lock", status.LockAmount, "wallet", s.sender != nil)
+	return
+}
+
+// Deposit the bond of lock amount for the relayer accounts not bonded yet
+func (s *Submitter) Deposit(ctx context.Context) (err error) {
+	if s.sender == nil {
+		return fmt.Errorf("No wallet configured for submitter of chain %s", s.name)
+	}
+	status, err := s.BridgeStatus()
+	if err != nil {
+		return
+	}
+	if status.Paused&PAUSED_DEPOSIT != 0 {
+		return fmt.Errorf("%w: bridge %s on %s paused deposits, flags %d", msg.ERR_CONTRACT_PAUSED, status.Contract, s.name, status.Paused)
+	}
+	data, err := s.abi.Pack("deposit")
+	if err != nil {
+		return
+	}
+	for _, account := range status.Unbonded() {
+		if status.Bonds[account].Sign() > 0 {
+			// Contract only accepts deposit of the exact lock amount from accounts without balance
+			return fmt.Errorf("Account %s has partial bond %s, withdraw it before deposit", account, status.Bonds[account])
+		}
+		sender := s.sender.Get(common.HexToAddress(account))
+		tx, err := sender.SendValue(ctx, s.hsContract, status.LockAmount, data, 0)
+		if err != nil {
+			return fmt.Errorf("Deposit from %s error %v", account, err)
+		}
+		_, err = sender.Confirm(ctx, tx.Hash())
+		if err != nil {
+			return fmt.Errorf("Deposit tx %s from %s failed %v", tx.Hash().Hex(), account, err)
+		}
+		log.Info("Deposited relayer bond", "chain", s.name, "account", account, "amount", status.LockAmount, "hash", tx.Hash().Hex())
+	}
+	return
+}
+
+// PauseFlags reads the pause flags of the bridge
+func (s *Submitter) PauseFlags() (flags uint64, err error) {
+	caller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
+	if err != nil {
+		return
+	}
+	paused, err := caller.Paused(nil)
+	if err != nil {
+		return 0, fmt.Errorf("Get bridge paused error %v", err)
+	}
+	return paused.Uint64(), nil
+}
+
+// Suspended returns the pause flags of the last poll if the bridge paused adding blocks, otherwise zero
+func (s *Submitter) Suspended() (flags uint64) {
+	flags = atomic.LoadUint64(&s.paused)
+	if flags&PAUSED_ADD_BLOCK == 0 {
+		return 0
+	}
+	return
+}
+
+// PauseCheck is the interval between polls of the pause flags
+func (s *Submitter) PauseCheck() time.Duration {
+	if s.config.PauseCheck > 0 {
+		return time.Duration(s.config.PauseCheck) * time.Second
+	}
+	return PAUSE_CHECK * time.Second
+}
+
+// Poll the bridge pause flags until the context is done
+func (s *Submitter) watchPaused(ctx context.Context) {
+	ticker := time.NewTicker(s.PauseCheck())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkPaused()
+		}
+	}
+}
+
+// CheckSuspended polls the pause flags, returns true if the bridge paused adding blocks
+func (s *Submitter) CheckSuspended() bool {
+	return s.checkPaused()&PAUSED_ADD_BLOCK != 0
+}
+
+// Update the pause flags, the last known flags are kept on failure
+func (s *Submitter) checkPaused() (flags uint64) {
+	flags, err := s.PauseFlags()
+	if err != nil {
+		log.Error("Failed to check bridge pause flags", "chain", s.name, "err", err)
+		return atomic.LoadUint64(&s.paused)
+	}
+	last := atomic.SwapUint64(&s.paused, flags)
+	s.metrics.Paused.Update(int64(flags))
+	if (flags^last)&PAUSED_ADD_BLOCK == 0 {
+		return
+	}
+	if flags&PAUSED_ADD_BLOCK != 0 {
+		log.Warn("Bridge paused adding blocks, header submission suspended", "chain", s.name, "contract", s.hsContract.Hex(), "flags", flags)
+		alert.Raise(
+			alert.WARN, fmt.Sprintf("contract_paused/%d/%d", s.config.ChainId, s.config.Submitter.ChainId),
+			"Header submission suspended", "Bridge %s on %s paused adding blocks, flags %d", s.hsContract.Hex(), s.name, flags,
+		)
+	} else {
+		log.Info("Bridge unpaused adding blocks, header submission resumed", "chain", s.name, "contract", s.hsContract.Hex(), "flags", flags)
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+)
+
+func TestBridgeReady(t *testing.T) {
+	lock := big.NewInt(100)
+	cases := []struct {
+		name          string
+		status        BridgeStatus
+		uninitialized bool
+		unbonded      bool
+	}{
+		{"uninitialized without wallet", BridgeStatus{LockAmount: lock}, true, false},
+		{"initialized without wallet", BridgeStatus{Initialized: true, LockAmount: lock}, false, false},
+		{"paused without wallet", BridgeStatus{Initialized: true, Paused: PAUSED_ADD_BLOCK, LockAmount: lock}, false, false},
+		{"bonded accounts", BridgeStatus{Initialized: true, LockAmount: lock, Bonds: map[string]*big.Int{"a": big.NewInt(100), "b": big.NewInt(200)}}, false, false},
+		{"unbonded account", BridgeStatus{Initialized: true, LockAmount: lock, Bonds: map[string]*big.Int{"a": big.NewInt(100), "b": big.NewInt(99)}}, false, true},
+		{"uninitialized with unbonded account", BridgeStatus{LockAmount: lock, Bonds: map[string]*big.Int{"a": big.NewInt(0)}}, true, false},
+	}
+	for _, c := range cases {
+		err := c.status.Ready("eth")
+		if uninitialized := errors.Is(err, msg.ERR_CONTRACT_UNINITIALIZED); uninitialized != c.uninitialized {
+			t.Fatalf("%s: expect uninitialized %v, got %v", c.name, c.uninitialized, err)
+		}
+		if unbonded := err != nil && !c.uninitialized; unbonded != c.unbonded {
+			t.Fatalf("%s: expect unbonded %v, got %v", c.name, c.unbonded, err)
+		}
+	}
+}
+
+func TestSuspended(t *testing.T) {
+	s := &Submitter{config: &config.HeaderSyncConfig{}}
+	for _, c := range []struct {
+		paused    uint64
+		suspended uint64
+	}{
+		{0, 0},
+		{PAUSED_DEPOSIT | PAUSED_WITHDRAW, 0},
+		{PAUSED_ADD_BLOCK, PAUSED_ADD_BLOCK},
+		{PAUSED_ADD_BLOCK | PAUSED_DEPOSIT, PAUSED_ADD_BLOCK | PAUSED_DEPOSIT},
+	} {
+		s.paused = c.paused
+		if flags := s.Suspended(); flags != c.suspended {
+			t.Fatalf("paused %d: expect suspended flags %d, got %d", c.paused, c.suspended, flags)
+		}
+	}
+
+	if s.PauseCheck() != PAUSE_CHECK*time.Second {
+		t.Fatalf("expect default pause check interval, got %v", s.PauseCheck())
+	}
+	s.config.PauseCheck = 5
+	if s.PauseCheck() != 5*time.Second {
+		t.Fatalf("expect configured pause check interval, got %v", s.PauseCheck())
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"math/big"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 
 	ethcommon "github.com/polynetwork/bridge-common/chains/eth"
@@ -18,37 +18,57 @@ import (
 	"github.com/top/top-relayer/abi/bridge"
 	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/metrics"
 	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
+	"github.com/top/top-relayer/relayer/sender"
+	"github.com/top/top-relayer/relayer/submit"
+	"github.com/top/top-relayer/store"
 )
 
 type Submitter struct {
 	context.Context
 	wg         *sync.WaitGroup
-	sdk        *ethcommon.SDK
+	conn       *live.Conn
 	name       string
 	wallet     wallet.IWallet
 	config     *config.HeaderSyncConfig
 	hsContract common.Address
 	composer   msg.SrcComposer
+	sender     *sender.Pool
+	abi        *abi.ABI
+	metrics    *metrics.HeaderSync
+	pipeline   *submit.Pipeline
+	paused     uint64 // bridge pause flags of the last poll
 
-	// Check last header commit
-	lastCommit   uint64
-	lastCheck    uint64
 	blocksToWait uint64
 }
 
+// Supports checks if the chain is eth compatible
+func Supports(chainId uint64) bool {
+	switch base.Family(chainId) {
+	case base.FAMILY_ETH, base.FAMILY_PARLIA:
+		return true
+	}
+	return false
+}
+
 func (s *Submitter) Init(config *config.HeaderSyncConfig) (err error) {
-	if config.ChainId != base.ETH {
-		return fmt.Errorf("eth submit invalid chain id %d", config.ChainId)
+	if !Supports(config.Submitter.ChainId) {
+		return fmt.Errorf("eth submit invalid chain id %d", config.Submitter.ChainId)
 	}
 
 	s.config = config
-	s.sdk, err = ethcommon.WithOptions(config.ChainId, config.Nodes, time.Minute, 1)
+	s.abi, err = bridge.BridgeMetaData.GetAbi()
+	if err != nil {
+		return
+	}
+	s.conn, err = live.Dial(config.Submitter.ChainId, config.Submitter.Nodes, live.FromConfig(config))
 	if err != nil {
 		return
 	}
 	if config.Submitter.Wallet != nil {
-		sdk, err := ethcommon.WithOptions(config.ChainId, config.Submitter.Wallet.Nodes, time.Minute, 1)
+		sdk, err := ethcommon.WithOptions(config.Submitter.ChainId, config.Submitter.Wallet.Nodes, time.Minute, 1)
 		if err != nil {
 			return err
 		}
@@ -59,135 +79,64 @@ func (s *Submitter) Init(config *config.HeaderSyncConfig) (err error) {
 		}
 
 		s.wallet = w.Upgrade()
+		s.sender, err = sender.NewPool(sdk, w, s.abi, config.Submitter)
+		if err != nil {
+			return err
+		}
 	}
 	s.name = base.GetChainName(config.Submitter.ChainId)
+	s.blocksToWait = base.BlocksToWait(config.ChainId)
+	s.metrics = metrics.ForHeaderSync(config.ChainId, config.Submitter.ChainId)
 	s.hsContract = common.HexToAddress(config.Submitter.HSContract)
+	s.pipeline = &submit.Pipeline{
+		Target: s, Name: s.name, Config: config, Conn: s.conn, Sender: s.sender, Metrics: s.metrics, Contract: s.hsContract,
+	}
 	return
 }
 
-func (s *Submitter) SDK() *ethcommon.SDK {
-	return s.sdk
-}
-
-func (s *Submitter) Hook(ctx context.Context, wg *sync.WaitGroup, ch <-chan msg.Message) error {
-	s.Context = ctx
-	s.wg = wg
-	return nil
-}
-
-func (s *Submitter) SubmitHeadersWithLoop(chainId uint64, headers [][]byte, header *msg.Header) (err error) {
-	start := time.Now()
-	h := uint64(0)
-
-	if chainId != base.TOP {
+// Reload applies the node list, batch and gas changes in place, the header sync position is kept
+func (s *Submitter) Reload(config *config.HeaderSyncConfig) (err error) {
+	err = s.conn.Reload(config.Submitter.Nodes, live.FromConfig(config))
+	if err != nil || s.sender == nil {
 		return
 	}
-
-	if len(headers) > 0 {
-		err = s.submitHeadersWithLoop(chainId, headers, header)
-		if err == nil && header != nil {
-			// Check last commit every 4 successful submit
-			if s.lastCommit > 0 && s.lastCheck > 3 {
-				s.lastCheck = 0
-				height, e := s.GetSideChainHeight(chainId)
-				if e != nil {
-					log.Error("Get side chain header height failure", "err", e)
-				} else if height < s.lastCommit {
-					log.Error("Chain header submit confirm check failure", "chain", s.name, "height", height, "last_submit", s.lastCommit)
-					err = msg.ERR_HEADER_MISSING
-				} else {
-					log.Info("Chain header submit confirm check success", "chain", s.name, "height", height, "last_submit", s.lastCommit)
-				}
-			} else {
-				s.lastCheck++
-			}
-		}
-	}
-	if header != nil {
-		h = header.Height
-		if err == nil {
-			s.lastCommit = header.Height // Mark last commit
-		}
-	}
-	log.Info("Submit headers to eth", "chain", chainId, "size", len(headers), "height", h, "elapse", time.Since(start), "err", err)
-	return
+	return s.sender.ReloadGas(config.Submitter.Gas)
 }
 
-func (s *Submitter) submitHeadersWithLoop(chainId uint64, headers [][]byte, header *msg.Header) error {
-	attempt := 0
-	var ok bool
-	for {
-		var err error
-		if header != nil {
-			ok, err = s.CheckHeaderExistence(header)
-			if ok {
-				return nil
-			}
-			if err != nil {
-				log.Error("Failed to check header existence", "chain", chainId, "height", header.Height)
-			}
-		}
-
-		if err == nil {
-			attempt += 1
-			_, err = s.SubmitHeaders(chainId, headers)
-			if err == nil {
-				return nil
-			}
-			info := err.Error()
-			if strings.Contains(info, "parent header not exist") ||
-				strings.Contains(info, "missing required field") ||
-				strings.Contains(info, "parent block failed") ||
-				strings.Contains(info, "span not correct") ||
-				strings.Contains(info, "VerifySpan err") {
-				//NOTE: reset header height back here
-				log.Error("Possible hard fork, will rollback some blocks", "chain", chainId, "err", err)
-				return msg.ERR_HEADER_INCONSISTENT
-			}
-			log.Error("Failed to submit header to poly", "chain", chainId, "err", err)
-		}
-		select {
-		case <-s.Done():
-			log.Warn("Header submitter exiting with headers not submitted", "chain", chainId)
-			return nil
-		default:
-			if attempt > 30 {
-				log.Error("Header submit too many failed attempts", "chain", chainId, "attempts", attempt)
-				return msg.ERR_HEADER_SUBMIT_FAILURE
-			}
-			time.Sleep(time.Second)
-		}
-	}
+func (s *Submitter) SDK() *ethcommon.SDK {
+	return s.conn.SDK()
 }
 
-func (s *Submitter) SubmitHeaders(chainId uint64, headers [][]byte) (hash string, err error) {
-	hscaller, err := bridge.NewBridgeTransactor(s.hsContract, s.sdk.Node())
-	if err != nil {
-		return "", fmt.Errorf("Proccess: fail to SubmitHeaders")
-	}
-
-	tx, err := hscaller.AddLightClientBlock(nil, headers[0])
-	if err != nil {
-		return "", err
+// Status of the submitter wallet accounts
+func (s *Submitter) Wallets() (list []sender.Status, err error) {
+	if s.sender == nil {
+		return
 	}
+	return s.sender.Status()
+}
 
-	hash, err = s.wallet.Send(s.hsContract, big.NewInt(0), 0, nil, nil, tx.Data())
-	txHash := common.HexToHash(hash)
-
-	_, _, _, err = s.sdk.Node().Confirm(txHash, 0, 10)
-	if err == nil {
-		log.Info("Submitted header to top", "chain", chainId, "hash", hash)
-	}
-	return
+func (s *Submitter) Hook(ctx context.Context, wg *sync.WaitGroup, ch <-chan msg.Message) error {
+	s.Context = ctx
+	s.wg = wg
+	return nil
 }
 
 func (s *Submitter) submit(tx *msg.Tx) error {
 	return nil
 }
 
+// Stop waits for the sync loop to exit, it exits after the header channel is closed and drained,
+// or the sync context is done.
 func (s *Submitter) Stop() error {
-	s.wg.Wait()
-	return nil
+	if s.pipeline == nil {
+		return nil
+	}
+	return s.pipeline.Stop()
+}
+
+// Pack the header submission call data
+func (s *Submitter) Pack(header *msg.Header) ([]byte, error) {
+	return s.abi.Pack("addLightClientBlock", header.Data)
 }
 
 func (s *Submitter) CollectSigs(tx *msg.Tx) (err error) {
@@ -224,32 +173,34 @@ func (s *Submitter) ReadyBlock() (height uint64) {
 }
 
 func (s *Submitter) StartSync(
-	ctx context.Context, wg *sync.WaitGroup, reset chan<- uint64,
+	ctx context.Context, wg *sync.WaitGroup, reset chan<- msg.Reset, state *store.State,
 ) (ch chan msg.Header, err error) {
 	s.Context = ctx
 	s.wg = wg
 
-	if s.config.Batch == 0 {
-		s.config.Batch = 1
+	if s.config.ChainId != base.TOP {
+		return nil, fmt.Errorf("Invalid header sync source chain id %d", s.config.ChainId)
 	}
-	if s.config.Buffer == 0 {
-		s.config.Buffer = 2 * s.config.Batch
+
+	err = s.Preflight()
+	if err != nil {
+		return
 	}
-	if s.config.Timeout == 0 {
-		s.config.Timeout = 1
+	s.checkPaused()
+	go s.watchPaused(ctx)
+	if s.sender != nil {
+		go s.sender.Watch(ctx, time.Minute)
 	}
-
-	if s.config.ChainId == 0 {
-		return nil, fmt.Errorf("Invalid header sync side chain id")
+	var loop func(<-chan msg.Header)
+	if s.config.Schedule {
+		loop = s.syncHeaderScheduleLoop
 	}
-
-	ch = make(chan msg.Header, s.config.Buffer)
-	go s.startSync(ch, reset)
+	ch = s.pipeline.Start(ctx, reset, state, loop)
 	return
 }
 
 func (s *Submitter) GetSideChainHeight(chainId uint64) (height uint64, err error) {
-	hscaller, err := bridge.NewBridgeCaller(s.hsContract, s.sdk.Node())
+	hscaller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
 	if err != nil {
 		return 0, fmt.Errorf("Proccess: fail to get side chain height by chain id %d", chainId)
 	}
@@ -259,7 +210,7 @@ func (s *Submitter) GetSideChainHeight(chainId uint64) (height uint64, err error
 }
 
 func (s *Submitter) GetSideChainHeader(chainId, height uint64) (hash []byte, err error) {
-	hscaller, err := bridge.NewBridgeCaller(s.hsContract, s.sdk.Node())
+	hscaller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
 	if err != nil {
 		return nil, fmt.Errorf("Proccess: fail to get side chain height by height %d", height)
 	}
@@ -278,84 +229,57 @@ func (s *Submitter) CheckHeaderExistence(header *msg.Header) (ok bool, err error
 	return
 }
 
-func (s *Submitter) syncHeaderLoop(ch <-chan msg.Header, reset chan<- uint64) {
-	for {
-		select {
-		case <-s.Done():
-			return
-		case header, ok := <-ch:
-			if !ok {
-				return
-			}
-			// NOTE err reponse here will revert header sync with delta - 2
-			headers := [][]byte{header.Data}
-			if header.Data == nil {
-				headers = nil
-			}
-			err := s.SubmitHeadersWithLoop(s.config.ChainId, headers, &header)
-			if err != nil {
-				reset <- header.Height - 2
-			}
-		}
+// Blocks of target chain to scan for light client block hash events
+const EVENT_SCAN_BLOCKS = 5000
+
+func (s *Submitter) BlockHashEvents() (reverted, added []msg.BlockHash, err error) {
+	filterer, err := bridge.NewBridgeFilterer(s.hsContract, s.SDK().Node())
+	if err != nil {
+		return
+	}
+	latest, err := s.SDK().Node().GetLatestHeight()
+	if err != nil {
+		return
+	}
+	opts := &bind.FilterOpts{End: &latest, Context: context.Background()}
+	if latest > EVENT_SCAN_BLOCKS {
+		opts.Start = latest - EVENT_SCAN_BLOCKS
 	}
-}
 
-func (s *Submitter) syncHeaderBatchLoop(ch <-chan msg.Header, reset chan<- uint64) {
-	headers := [][]byte{}
-	commit := false
-	duration := time.Duration(s.config.Timeout) * time.Second
-	var (
-		height uint64
-		hdr    *msg.Header
-	)
-
-COMMIT:
-	for {
-		select {
-		case <-s.Done():
-			break COMMIT
-		case header, ok := <-ch:
-			if ok {
-				hdr = &header
-				height = header.Height
-				if hdr.Data == nil {
-					// Update header sync height
-					commit = true
-				} else {
-					headers = append(headers, header.Data)
-					commit = len(headers) >= s.config.Batch
-				}
-			} else {
-				commit = len(headers) > 0
-				break COMMIT
-			}
-		case <-time.After(duration):
-			commit = len(headers) > 0
-		}
-		if commit {
-			commit = false
-			// NOTE err reponse here will revert header sync with delta -100
-			err := s.SubmitHeadersWithLoop(s.config.ChainId, headers, hdr)
-			if err != nil {
-				reset <- height - uint64(len(headers)) - 2
-			}
-			headers = [][]byte{}
-		}
+	revertedEvents, err := filterer.FilterBlockHashReverted(opts, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Filter block hash reverted events error %v", err)
 	}
-	if len(headers) > 0 {
-		s.SubmitHeadersWithLoop(s.config.ChainId, headers, hdr)
+	defer revertedEvents.Close()
+	for revertedEvents.Next() {
+		ev := revertedEvents.Event
+		reverted = append(reverted, msg.BlockHash{Height: ev.Height, Hash: common.Hash(ev.BlockHash).Hex()})
 	}
+	if err = revertedEvents.Error(); err != nil {
+		return
+	}
+
+	addedEvents, err := filterer.FilterBlockHashAdded(opts, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Filter block hash added events error %v", err)
+	}
+	defer addedEvents.Close()
+	for addedEvents.Next() {
+		ev := addedEvents.Event
+		added = append(added, msg.BlockHash{Height: ev.Height, Hash: common.Hash(ev.BlockHash).Hex()})
+	}
+	err = addedEvents.Error()
+	return
 }
 
-func (s *Submitter) startSync(ch <-chan msg.Header, reset chan<- uint64) {
-	if s.config.Batch == 1 {
-		s.syncHeaderLoop(ch, reset)
-	} else {
-		s.syncHeaderBatchLoop(ch, reset)
+func (s *Submitter) GetHeightByHash(hash []byte) (height uint64, err error) {
+	caller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
+	if err != nil {
+		return
 	}
-	log.Info("Header sync exiting loop now")
+	return caller.GetHeightByHash(nil, common.BytesToHash(hash))
 }
 
 func (s *Submitter) Peer() *ethcommon.SDK {
-	return s.sdk
+	return s.SDK()
 }
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
 
 	"github.com/polynetwork/bridge-common/chains"
 	"github.com/polynetwork/bridge-common/chains/eth"
@@ -15,47 +17,84 @@ import (
 	"github.com/top/top-relayer/abi/hsc"
 	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
 )
 
+// HeaderEncoder encodes the block header in the format the light client contract verifies
+type HeaderEncoder func(*types.Header) ([]byte, error)
+
+func EncodeJSON(hdr *types.Header) ([]byte, error) {
+	return hdr.MarshalJSON()
+}
+
+// Parlia header seal and validator set in extra data are verified against the rlp encoding
+func EncodeRLP(hdr *types.Header) ([]byte, error) {
+	return rlp.EncodeToBytes(hdr)
+}
+
 type Listener struct {
-	sdk        *eth.SDK
-	peer       *eth.SDK
+	conn       *live.Conn
 	hsContract common.Address
 	config     *config.HeaderSyncConfig
 	name       string
+	encode     HeaderEncoder
+}
+
+// NewListener creates a listener encoding headers with the encoder, json by default
+func NewListener(encode HeaderEncoder) *Listener {
+	return &Listener{encode: encode}
 }
 
 func (l *Listener) Init(config *config.HeaderSyncConfig, peerSdk *ethcommon.SDK) (err error) {
+	if !Supports(config.ChainId) {
+		return fmt.Errorf("eth listener invalid chain id %d", config.ChainId)
+	}
 	l.config = config
 	l.name = base.GetChainName(config.ChainId)
+	if l.encode == nil {
+		l.encode = EncodeJSON
+	}
 	l.hsContract = common.HexToAddress(config.Submitter.HSContract)
-	l.peer = peerSdk
-	l.sdk, err = eth.WithOptions(config.ChainId, config.Nodes, time.Minute, 1)
+	l.conn, err = live.Dial(config.ChainId, config.Nodes, live.FromConfig(config))
+	if err != nil {
+		return
+	}
+	l.conn.SetPeer(peerSdk)
 	return
 }
 
-func (l *Listener) Header(height uint64) (header []byte, hash []byte, err error) {
-	hdr, err := l.sdk.Node().HeaderByNumber(context.Background(), big.NewInt(int64(height)))
+func (l *Listener) Header(height uint64) (header *msg.Header, err error) {
+	hdr, err := l.conn.SDK().Node().HeaderByNumber(context.Background(), big.NewInt(int64(height)))
 	if err != nil {
 		err = fmt.Errorf("Fetch block header error %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 	log.Info("Fetched block header", "chain", l.name, "height", height, "hash", hdr.Hash().String())
-	hash = hdr.Hash().Bytes()
-	header, err = hdr.MarshalJSON()
+	header = &msg.Header{Height: height, Hash: hdr.Hash().Bytes(), Parent: hdr.ParentHash.Bytes(), Time: hdr.Time}
+	header.Data, err = l.encode(hdr)
+	return
+}
+
+// Reload applies the node list and listen check changes in place
+func (l *Listener) Reload(config *config.HeaderSyncConfig, peerSdk *ethcommon.SDK) (err error) {
+	err = l.conn.Reload(config.Nodes, live.FromConfig(config))
+	if err == nil {
+		l.conn.SetPeer(peerSdk)
+	}
 	return
 }
 
 func (l *Listener) ListenCheck() time.Duration {
 	duration := time.Second
-	if l.config.ListenCheck > 0 {
-		duration = time.Duration(l.config.ListenCheck) * time.Second
+	if check := l.conn.Load().ListenCheck; check > 0 {
+		duration = time.Duration(check) * time.Second
 	}
 	return duration
 }
 
 func (l *Listener) Nodes() chains.Nodes {
-	return l.sdk.ChainSDK
+	return l.conn.SDK().ChainSDK
 }
 
 func (l *Listener) ChainId() uint64 {
@@ -63,7 +102,10 @@ func (l *Listener) ChainId() uint64 {
 }
 
 func (l *Listener) Defer() int {
-	return l.config.Defer
+	if l.config.Defer > 0 {
+		return l.config.Defer
+	}
+	return int(base.BlocksToWait(l.config.ChainId))
 }
 
 func (l *Listener) Name() string {
@@ -71,16 +113,16 @@ func (l *Listener) Name() string {
 }
 
 func (l *Listener) SDK() *eth.SDK {
-	return l.sdk
+	return l.conn.SDK()
 }
 
 func (l *Listener) LatestHeight() (uint64, error) {
-	return l.sdk.Node().GetLatestHeight()
+	return l.conn.SDK().Node().GetLatestHeight()
 }
 
 //todo
 func (l *Listener) getSideChainHeight(chainId uint64) (height uint64, err error) {
-	hscaller, err := hsc.NewHscCaller(l.hsContract, l.peer.Node())
+	hscaller, err := hsc.NewHscCaller(l.hsContract, l.conn.Peer().Node())
 	if err != nil {
 		return 0, err
 	}
@@ -101,8 +143,8 @@ func (l *Listener) getSideChainHeight(chainId uint64) (height uint64, err error)
 }
 
 func (l *Listener) LastHeaderSync(force, last uint64) (height uint64, err error) {
-	if l.peer == nil {
-		err = fmt.Errorf("No poly sdk provided for listener", "chain", l.name)
+	if l.conn.Peer() == nil {
+		err = fmt.Errorf("No poly sdk provided for listener of chain %s", l.name)
 		return
 	}
 
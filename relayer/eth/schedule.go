@@ -0,0 +1,123 @@
+package eth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/abi/bridge"
+	"github.com/top/top-relayer/msg"
+)
+
+// Seconds between polls of the bridge state while waiting for an acceptable header
+const SCHEDULE_CHECK = 10
+
+// Light client state deciding the next header the bridge accepts
+type BridgeState struct {
+	CurrentHeight     uint64
+	NextTimestamp     uint64 // header timestamp required by the next block, zero if any newer block is accepted
+	NumBlockProducers uint64
+	time              time.Time
+}
+
+// Accepts checks if the header can be added as the next light client block
+func (b *BridgeState) Accepts(header *msg.Header) bool {
+	return header.Height > b.CurrentHeight && header.Time >= b.NextTimestamp
+}
+
+// Stale checks if the header can never be accepted by the bridge
+func (b *BridgeState) Stale(header *msg.Header) bool {
+	return header.Height <= b.CurrentHeight
+}
+
+func (s *Submitter) BridgeState() (state *BridgeState, err error) {
+	caller, err := bridge.NewBridgeCaller(s.hsContract, s.SDK().Node())
+	if err != nil {
+		return
+	}
+	res, err := caller.BridgeState(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Get bridge state error %v", err)
+	}
+	state = &BridgeState{
+		CurrentHeight:     res.CurrentHeight.Uint64(),
+		NextTimestamp:     res.NextTimestamp.Uint64(),
+		NumBlockProducers: res.NumBlockProducers.Uint64(),
+		time:              time.Now(),
+	}
+	return
+}
+
+// Submit the newest fetched header once the bridge accepts it, the headers in between are skipped
+func (s *Submitter) syncHeaderScheduleLoop(ch <-chan msg.Header) {
+	var (
+		state     *BridgeState
+		candidate *msg.Header // newest header waiting to be accepted
+		skipped   uint64
+		err       error
+	)
+	check := SCHEDULE_CHECK * time.Second
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.Done():
+			return
+		case header, ok := <-ch:
+			if !ok {
+				if candidate != nil {
+					log.Info("Scheduled header sync exiting with header not accepted yet", "chain", s.config.ChainId, "height", candidate.Height)
+				}
+				return
+			}
+			if header.Data == nil {
+				continue
+			}
+			if candidate != nil {
+				skipped++
+			}
+			candidate = &header
+			if len(ch) > 0 {
+				// Catch up with the newest buffered header first
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		if candidate == nil {
+			continue
+		}
+		if state == nil || time.Since(state.time) >= check {
+			state, err = s.BridgeState()
+			if err != nil {
+				log.Error("Failed to get bridge state", "chain", s.name, "err", err)
+				continue
+			}
+		}
+		if state.Stale(candidate) {
+			log.Info("Skipping header below light client height", "chain", s.config.ChainId, "height", candidate.Height, "current", state.CurrentHeight)
+			s.metrics.Skipped.Inc(int64(skipped + 1))
+			candidate, skipped = nil, 0
+			continue
+		}
+		if !state.Accepts(candidate) {
+			// Wait for a newer header or the bridge state to change
+			continue
+		}
+
+		if skipped > 0 {
+			log.Info("Skipped headers to the newest acceptable one", "chain", s.config.ChainId, "count", skipped, "height", candidate.Height)
+			s.metrics.Skipped.Inc(int64(skipped))
+			skipped = 0
+		}
+		log.Info("Submitting scheduled header", "chain", s.config.ChainId, "height", candidate.Height,
+			"time", candidate.Time, "next_timestamp", state.NextTimestamp, "current", state.CurrentHeight)
+		// NOTE err reponse here will revert header sync to the first failed height
+		failed, e := s.pipeline.SubmitHeadersWithLoop(s.config.ChainId, []msg.Header{*candidate}, candidate)
+		if e != nil {
+			s.pipeline.Reset(failed, e)
+		}
+		candidate, state = nil, nil
+	}
+}
@@ -0,0 +1,34 @@
+package eth
+
+import (
+	"testing"
+
+	"github.com/top/top-relayer/msg"
+)
+
+func TestBridgeState(t *testing.T) {
+	cases := []struct {
+		name    string
+		state   BridgeState
+		header  msg.Header
+		accepts bool
+		stale   bool
+	}{
+		{"next block", BridgeState{CurrentHeight: 10}, msg.Header{Height: 11}, true, false},
+		{"newer block", BridgeState{CurrentHeight: 10}, msg.Header{Height: 20}, true, false},
+		{"current block", BridgeState{CurrentHeight: 10}, msg.Header{Height: 10}, false, true},
+		{"older block", BridgeState{CurrentHeight: 10}, msg.Header{Height: 3}, false, true},
+		{"before next timestamp", BridgeState{CurrentHeight: 10, NextTimestamp: 100}, msg.Header{Height: 12, Time: 99}, false, false},
+		{"at next timestamp", BridgeState{CurrentHeight: 10, NextTimestamp: 100}, msg.Header{Height: 12, Time: 100}, true, false},
+		{"after next timestamp", BridgeState{CurrentHeight: 10, NextTimestamp: 100}, msg.Header{Height: 12, Time: 101}, true, false},
+		{"old block after next timestamp", BridgeState{CurrentHeight: 10, NextTimestamp: 100}, msg.Header{Height: 9, Time: 200}, false, true},
+	}
+	for _, c := range cases {
+		if accepts := c.state.Accepts(&c.header); accepts != c.accepts {
+			t.Fatalf("%s: expect accepts %v, got %v", c.name, c.accepts, accepts)
+		}
+		if stale := c.state.Stale(&c.header); stale != c.stale {
+			t.Fatalf("%s: expect stale %v, got %v", c.name, c.stale, stale)
+		}
+	}
+}
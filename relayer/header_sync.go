@@ -1,39 +1,65 @@
 package relayer
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/alert"
 	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/metrics"
 	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
+	"github.com/top/top-relayer/relayer/sender"
+	"github.com/top/top-relayer/store"
 )
 
 type HeaderSyncHandler struct {
 	context.Context
+	cancel    context.CancelFunc // stops fetching headers
+	abort     context.CancelFunc // aborts the submitter
 	wg        *sync.WaitGroup
 	listener  IChainListener
 	submitter IChainSubmitter
 	height    uint64
 	config    *config.HeaderSyncConfig
-	reset     chan uint64
+	reset     chan msg.Reset
+	state     *store.State
+	metrics   *metrics.HeaderSync
+	settings  live.Value // reloadable settings
+
+	// Status
+	fetched   uint64
+	resets    uint64
+	paused    int32
+	retryAt   int64 // unix nano time to retry after giving up
+	giveUps   int   // consecutive give ups without submission progress
+	giveUpAt  uint64
+	lastError atomic.Value
+	lastReorg atomic.Value
+	window    *HeaderWindow
 }
 
-func NewHeaderSyncHandler(config *config.HeaderSyncConfig) *HeaderSyncHandler {
-	return &HeaderSyncHandler{
+func NewHeaderSyncHandler(config *config.HeaderSyncConfig, state *store.State) (h *HeaderSyncHandler) {
+	h = &HeaderSyncHandler{
 		listener:  GetListener(config.ChainId),
 		submitter: GetSubmitter(config.Submitter.ChainId),
 		config:    config,
-		reset:     make(chan uint64, 1),
+		reset:     make(chan msg.Reset, 1),
+		state:     state,
+		metrics:   metrics.ForHeaderSync(config.ChainId, config.Submitter.ChainId),
+		window:    NewHeaderWindow(config.HeaderWindow),
 	}
+	h.settings.Store(live.FromConfig(config))
+	return
 }
 
 func (h *HeaderSyncHandler) Init(ctx context.Context, wg *sync.WaitGroup) (err error) {
-	h.Context = ctx
+	h.Context, h.cancel = context.WithCancel(ctx)
 	h.wg = wg
 
 	err = h.submitter.Init(h.config)
@@ -60,51 +86,31 @@ func (h *HeaderSyncHandler) monitor(ch chan<- uint64) {
 		case <-h.Done():
 			return
 		case <-timer.C:
-			switch h.config.ChainId {
-			case base.BSC, base.ETH:
+			if base.Family(h.config.ChainId) != base.FAMILY_TOP {
 				height, err := h.submitter.GetSideChainHeight(h.config.ChainId)
 				if err == nil {
 					ch <- height
 				}
-			default:
 			}
 		}
 	}
 }
 
-func (h *HeaderSyncHandler) RollbackToCommonAncestor(height, target uint64) uint64 {
-	log.Warn("Rolling header sync back to common ancestor", "current", height, "goal", target, "chain", h.config.ChainId)
-	switch h.config.ChainId {
-	case base.ETH, base.BSC:
-	default:
-		return target
-	}
+// Default blocks of lag to raise alert
+const LAG_ALERT = 1000
 
-	var (
-		a, b []byte
-		err  error
-	)
-	for {
-		// Check err here?
-		b, _ = h.submitter.GetSideChainHeader(h.config.ChainId, target)
-		if len(b) == 0 {
-			target--
-			continue
-		}
-		_, a, err = h.listener.Header(target)
-		if err == nil {
-			if bytes.Equal(a, b) {
-				log.Info("Found common ancestor", "chain", h.config.ChainId, "height", target)
-				return target
-			} else {
-				target--
-				continue
-			}
-		} else {
-			log.Error("RollbackToCommonAncestor error", "chain", h.config.ChainId, "height", target)
-			time.Sleep(time.Second)
-		}
+func (h *HeaderSyncHandler) lagAlert() uint64 {
+	if lag := h.settings.Load().LagAlert; lag > 0 {
+		return uint64(lag)
 	}
+	return LAG_ALERT
+}
+
+// Raise alert of the header sync direction
+func (h *HeaderSyncHandler) raise(severity alert.Severity, kind, title, format string, args ...interface{}) {
+	key := fmt.Sprintf("%s/%d/%d", kind, h.config.ChainId, h.config.Submitter.ChainId)
+	title = fmt.Sprintf("%s (%s -> %s)", title, base.GetChainName(h.config.ChainId), base.GetChainName(h.config.Submitter.ChainId))
+	alert.Raise(severity, key, title, format, args...)
 }
 
 func (h *HeaderSyncHandler) watch() {
@@ -117,25 +123,25 @@ func (h *HeaderSyncHandler) watch() {
 		case <-h.Done():
 			return
 		case <-ticker.C:
+			h.checkForceHeight()
 			height, err := h.listener.Nodes().Node().GetLatestHeight()
 			if err != nil {
 				log.Error("Watch chain latest height error", "chain", h.config.ChainId, "err", err)
 			} else if height > last {
 				log.Info("Latest chain height", "chain", h.config.ChainId, "height", height)
-				// h.latest.UpdateHeight(context.Background(), height)
+				h.metrics.SourceTip.Update(int64(height))
 				last = height
 			}
 
-			switch h.config.ChainId {
-			case base.BSC, base.ETH:
-				height, err = h.submitter.GetSideChainHeight(h.config.ChainId)
-				if err != nil {
-					log.Error("Watch chain sync height error", "chain", h.config.ChainId, "err", err)
-				} else {
-					log.Info("Latest chain sync height", "chain", h.config.ChainId, "height", height)
+			height, err = h.submitter.GetSideChainHeight(h.config.ChainId)
+			if err != nil {
+				log.Error("Watch chain sync height error", "chain", h.config.ChainId, "err", err)
+			} else {
+				log.Info("Latest chain sync height", "chain", h.config.ChainId, "height", height)
+				h.metrics.Synced.Update(int64(height))
+				if lag := h.lagAlert(); last > height+lag {
+					h.raise(alert.WARN, "lag", "Header sync lagging", "%d blocks behind source chain tip %d", last-height, last)
 				}
-			default:
-				height = 0
 			}
 		}
 	}
@@ -153,25 +159,23 @@ LOOP:
 	for {
 		select {
 		case reset := <-h.reset:
-			if reset < h.height && reset != 0 {
-				// Drain the headers buf
-			DRAIN:
-				for {
-					select {
-					case <-ch:
-					default:
-						break DRAIN
-					}
-				}
-
-				log.Info("Detected submit failure reset", "chain", h.config.ChainId, "value", reset)
-				h.height = h.RollbackToCommonAncestor(h.height, reset-1)
-			}
+			h.handleReset(ch, reset)
 		case <-h.Done():
 			break LOOP
 		default:
 		}
 
+		if h.Paused() || h.backingOff() {
+			select {
+			case <-h.Done():
+				break LOOP
+			case reset := <-h.reset:
+				h.handleReset(ch, reset)
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
 		h.height++
 		log.Debug("Header sync processing block", "height", h.height, "chain", h.config.ChainId)
 		if latest < h.height+confirms {
@@ -180,14 +184,28 @@ LOOP:
 				break LOOP
 			}
 		}
-		header, hash, err := h.listener.Header(h.height)
+		header, err := h.listener.Header(h.height)
 		log.Debug("Header sync fetched block header", "height", h.height, "chain", h.config.ChainId, "err", err)
 		if err == nil {
+			if ancestor, reorg := h.checkSourceReorg(header); reorg {
+				h.metrics.Rollbacks.Inc(1)
+				h.drain(ch)
+				h.rewind(ancestor)
+				continue
+			}
+			h.window.Push(header)
 			select {
-			case ch <- msg.Header{Data: header, Height: h.height, Hash: hash}:
+			case ch <- *header:
 			case <-h.Done():
 				break LOOP
 			}
+			atomic.StoreUint64(&h.fetched, h.height)
+			h.metrics.Fetched.Update(int64(h.height))
+			h.metrics.Buffer.Update(int64(len(ch)))
+			err = h.state.SetFetchHeight(h.height)
+			if err != nil {
+				log.Error("Failed to record header fetch height", "chain", h.config.ChainId, "height", h.height, "err", err)
+			}
 			continue
 		} else {
 			log.Error("Fetch block header error", "chain", h.config.ChainId, "height", h.height, "err", err)
@@ -198,23 +216,291 @@ LOOP:
 	close(ch)
 }
 
+func (h *HeaderSyncHandler) handleReset(ch chan msg.Header, reset msg.Reset) {
+	if reset.Err != nil {
+		atomic.AddUint64(&h.resets, 1)
+		h.lastError.Store(reset.Err.Error())
+	}
+	if reset.Height == 0 {
+		return
+	}
+	if msg.SubmitPolicy(reset.Err) == msg.POLICY_GIVE_UP {
+		delay := h.giveUp()
+		h.raise(alert.CRITICAL, "give_up", "Header sync backing off", "Submit failure at height %d: %v, retry in %s", reset.Height, reset.Err, delay)
+		if reset.Height <= h.height {
+			h.drain(ch)
+			h.rewind(reset.Height - 1)
+		}
+		return
+	}
+	if !reset.Force && reset.Height >= h.height {
+		return
+	}
+
+	h.drain(ch)
+
+	if reset.Force {
+		log.Info("Detected forced header sync reset", "chain", h.config.ChainId, "value", reset.Height)
+		h.rewind(reset.Height - 1)
+		return
+	}
+	log.Info("Detected submit failure reset", "chain", h.config.ChainId, "value", reset.Height, "err", reset.Err)
+	h.metrics.Rollbacks.Inc(1)
+	if reset.Err == msg.ERR_HEADER_SUBMIT_FAILURE {
+		h.raise(alert.CRITICAL, "submit_failure", "Header submit failure", "Too many failed attempts at height %d", reset.Height)
+	} else {
+		h.raise(alert.WARN, "rollback", "Header sync rollback", "Rolling back from height %d: %v", reset.Height, reset.Err)
+	}
+	target := reset.Height - 1
+	if reset.Err == msg.ERR_HEADER_INCONSISTENT {
+		// Possible fork, skip back further before searching the common ancestor
+		skip := base.BlocksToSkip(h.config.ChainId)
+		if target > skip {
+			target -= skip
+		}
+	}
+	h.rewind(h.RollbackToCommonAncestor(reset.Height, target))
+}
+
+// Drain the headers buf
+func (h *HeaderSyncHandler) drain(ch chan msg.Header) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
+
+// Rewind the header sync to resume from the next height
+func (h *HeaderSyncHandler) rewind(height uint64) {
+	h.height = height
+	h.window.Truncate(height)
+	atomic.StoreUint64(&h.fetched, height)
+	err := h.state.Rewind(height)
+	if err != nil {
+		log.Error("Failed to record header sync reset", "chain", h.config.ChainId, "height", height, "err", err)
+	}
+}
+
+// Seconds to back off after the first give up, doubled for each consecutive one until the max
+const (
+	GIVE_UP_BACKOFF     = 60
+	GIVE_UP_BACKOFF_MAX = 3600
+)
+
+// giveUp suspends fetching headers for a backoff period, which grows while the submit height does not progress
+func (h *HeaderSyncHandler) giveUp() (delay time.Duration) {
+	submitted, _ := h.state.SubmitHeight()
+	if submitted > h.giveUpAt {
+		h.giveUps = 0
+	}
+	h.giveUpAt = submitted
+	delay = GIVE_UP_BACKOFF * time.Second
+	for i := 0; i < h.giveUps && delay < GIVE_UP_BACKOFF_MAX*time.Second; i++ {
+		delay *= 2
+	}
+	if delay > GIVE_UP_BACKOFF_MAX*time.Second {
+		delay = GIVE_UP_BACKOFF_MAX * time.Second
+	}
+	h.giveUps++
+	atomic.StoreInt64(&h.retryAt, time.Now().Add(delay).UnixNano())
+	log.Warn("Header sync backing off", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId, "retry", delay, "count", h.giveUps)
+	return
+}
+
+func (h *HeaderSyncHandler) backingOff() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&h.retryAt)
+}
+
+// Pause stops fetching new headers, headers already buffered will still be submitted
+func (h *HeaderSyncHandler) Pause() {
+	atomic.StoreInt32(&h.paused, 1)
+	log.Warn("Header sync paused", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId)
+}
+
+// Resume fetching headers, a give up backoff in progress is cancelled as well
+func (h *HeaderSyncHandler) Resume() {
+	atomic.StoreInt32(&h.paused, 0)
+	atomic.StoreInt64(&h.retryAt, 0)
+	log.Warn("Header sync resumed", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId)
+}
+
+func (h *HeaderSyncHandler) Paused() bool {
+	return atomic.LoadInt32(&h.paused) == 1
+}
+
+// Resync forces the header sync to restart from the height
+func (h *HeaderSyncHandler) Resync(height uint64) (err error) {
+	if height == 0 {
+		return fmt.Errorf("Invalid resync height")
+	}
+	select {
+	case h.reset <- msg.Reset{Height: height, Force: true}:
+		return
+	default:
+		return fmt.Errorf("Header sync reset is in progress, try again later")
+	}
+}
+
+// Apply the forced sync height set by command while running
+func (h *HeaderSyncHandler) checkForceHeight() {
+	force, err := h.state.ForceHeight()
+	if err != nil || force == 0 {
+		return
+	}
+	if h.Resync(force+1) == nil {
+		log.Warn("Applying forced header sync height", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId, "height", force)
+		h.state.ClearForceHeight(force)
+	}
+}
+
+// Header sync status of the direction
+type HeaderSyncStatus struct {
+	Chain     uint64
+	Target    uint64
+	Height    uint64 // last fetched header height
+	Submitted uint64 // last submitted header height
+	Resets    uint64
+	LastError string `json:",omitempty"`
+	LastReorg *Reorg `json:",omitempty"`
+	Paused    bool
+	RetryAt   int64  `json:",omitempty"` // unix time to retry after giving up
+	Suspended bool   // submission suspended while the light client contract is paused
+	Flags     uint64 `json:",omitempty"` // pause flags of the light client contract
+}
+
+func (h *HeaderSyncHandler) Status() (status HeaderSyncStatus) {
+	status = HeaderSyncStatus{
+		Chain:  h.config.ChainId,
+		Target: h.config.Submitter.ChainId,
+		Height: atomic.LoadUint64(&h.fetched),
+		Resets: atomic.LoadUint64(&h.resets),
+		Paused: h.Paused(),
+	}
+	if h.backingOff() {
+		status.RetryAt = atomic.LoadInt64(&h.retryAt) / int64(time.Second)
+	}
+	status.Submitted, _ = h.state.SubmitHeight()
+	status.LastError, _ = h.lastError.Load().(string)
+	status.LastReorg, _ = h.lastReorg.Load().(*Reorg)
+	if watcher, ok := h.submitter.(IPauseWatcher); ok {
+		status.Flags = watcher.Suspended()
+		status.Suspended = status.Flags != 0
+	}
+	return
+}
+
+func (h *HeaderSyncHandler) Wallets() ([]sender.Status, error) {
+	return h.submitter.Wallets()
+}
+
 func (h *HeaderSyncHandler) Start() (err error) {
+	// Forced sync height set by command
+	force, err := h.state.ForceHeight()
+	if err != nil {
+		log.Error("Failed to read forced header sync height", "chain", h.config.ChainId, "err", err)
+		force = 0
+	}
 	// Last successful sync height
-	h.height, err = h.listener.LastHeaderSync(0, 0)
+	h.height, err = h.listener.LastHeaderSync(force, 0)
+	if err == nil && force > 0 {
+		h.rewind(force)
+		h.state.ClearForceHeight(force)
+	}
 	if err != nil {
-		return
+		local, e := h.state.SubmitHeight()
+		if e != nil || local == 0 {
+			return
+		}
+		log.Warn("Failed to get header sync height from chain, will resume from local state", "chain", h.config.ChainId, "height", local, "err", err)
+		h.height, err = local, nil
+	}
+	// Headers fetched in last run with submission txs pending are not fetched again
+	last := h.height
+	if force == 0 {
+		resume, e := h.state.Resume(h.height)
+		if e != nil {
+			log.Error("Failed to read local header fetch progress", "chain", h.config.ChainId, "err", e)
+		} else {
+			h.height = resume
+		}
 	}
-	log.Info("Header sync will start...", "height", h.height+1, "force", 0, "last", 0, "chain", h.config.ChainId)
-	ch, err := h.submitter.StartSync(h.Context, h.wg, h.reset)
+	log.Info("Header sync will start...", "height", h.height+1, "force", force, "last", last, "chain", h.config.ChainId)
+	h.fetched = h.height
+	// Submitter runs with a separate context, so buffered headers can be flushed on stop
+	ctx, abort := context.WithCancel(context.Background())
+	ch, err := h.submitter.StartSync(ctx, h.wg, h.reset, h.state)
 	if err != nil {
+		abort()
 		return
 	}
+	h.abort = abort
 	go h.watch()
 	go h.start(ch)
 	return
 }
 
+// Default seconds to wait for the submitter to flush buffered headers on stop
+const STOP_TIMEOUT = 60
+
+// Stop fetching new headers and wait for buffered headers to be submitted and confirmed until the deadline.
+// The submitter is aborted after the deadline, and the fetch height is rewound to the last submitted or pending header.
 func (h *HeaderSyncHandler) Stop() (err error) {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	if h.abort == nil {
+		return
+	}
+	timeout := h.settings.Load().StopTimeout
+	if timeout <= 0 {
+		timeout = STOP_TIMEOUT
+	}
+	log.Info("Stopping header sync", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId, "timeout", timeout)
+
+	done := make(chan error, 1)
+	go func() { done <- h.submitter.Stop() }()
+	deadline := time.After(time.Duration(timeout) * time.Second)
+WAIT:
+	for {
+		select {
+		case err = <-done:
+			break WAIT
+		case reset := <-h.reset:
+			// Fetcher has exited, resets are not applied any more
+			log.Warn("Ignored header sync reset on stop", "chain", h.config.ChainId, "height", reset.Height, "err", reset.Err)
+		case <-deadline:
+			log.Warn("Header sync stop timeout, aborting submitter", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId)
+			h.abort()
+			err = <-done
+			break WAIT
+		}
+	}
+	h.abort()
+
+	// Record the exact progress, headers fetched but not submitted will be fetched again on restart
+	submitted, e := h.state.SubmitHeight()
+	if e != nil {
+		log.Error("Failed to read header submit height on stop", "chain", h.config.ChainId, "err", e)
+		return
+	}
+	fetched := atomic.LoadUint64(&h.fetched)
+	if fetched > submitted {
+		log.Warn("Header sync stopped with headers not submitted", "chain", h.config.ChainId, "from", submitted+1, "to", fetched)
+	}
+	// Keep the headers with submission txs pending as fetched, they are confirmed on restart
+	resume, e := h.state.Resume(submitted)
+	if e == nil && resume > 0 {
+		e = h.state.SetFetchHeight(resume)
+	}
+	if e != nil {
+		log.Error("Failed to record header fetch height on stop", "chain", h.config.ChainId, "height", resume, "err", e)
+	}
+	pending, _ := h.state.Pending()
+	log.Info("Header sync stopped", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId, "submitted", submitted, "pending_txs", len(pending))
 	return
 }
 
@@ -0,0 +1,168 @@
+package relayer
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/metrics"
+)
+
+// HttpServer exposes the header sync status and admin actions of the running relayer
+type HttpServer struct {
+	sync.RWMutex
+	server   *http.Server
+	token    string
+	handlers []*HeaderSyncHandler
+}
+
+func NewHttpServer(host string, port int, token string, roles []Handler) *HttpServer {
+	s := &HttpServer{token: token}
+	s.SetRoles(roles)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/status", s.Status)
+	mux.HandleFunc("/api/v1/wallets", s.Wallets)
+	mux.HandleFunc("/api/v1/pause", s.admin(s.Pause))
+	mux.HandleFunc("/api/v1/resume", s.admin(s.Resume))
+	mux.HandleFunc("/api/v1/resync", s.admin(s.Resync))
+	mux.Handle("/metrics", metrics.Handler())
+	s.server = &http.Server{Addr: fmt.Sprintf("%s:%d", host, port), Handler: mux}
+	return s
+}
+
+// SetRoles replaces the header sync handlers served
+func (s *HttpServer) SetRoles(roles []Handler) {
+	handlers := []*HeaderSyncHandler{}
+	for _, role := range roles {
+		if h, ok := role.(*HeaderSyncHandler); ok {
+			handlers = append(handlers, h)
+		}
+	}
+	s.Lock()
+	s.handlers = handlers
+	s.Unlock()
+}
+
+func (s *HttpServer) list() []*HeaderSyncHandler {
+	s.RLock()
+	defer s.RUnlock()
+	return s.handlers
+}
+
+// Start serving until the context is done
+func (s *HttpServer) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(c)
+	}()
+	go func() {
+		log.Info("Starting http server", "addr", s.server.Addr)
+		err := s.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("Http server exited with error", "err", err)
+		}
+	}()
+}
+
+func (s *HttpServer) Status(w http.ResponseWriter, r *http.Request) {
+	list := []HeaderSyncStatus{}
+	for _, h := range s.list() {
+		list = append(list, h.Status())
+	}
+	reply(w, http.StatusOK, list)
+}
+
+func (s *HttpServer) Wallets(w http.ResponseWriter, r *http.Request) {
+	list := []WalletStatus{}
+	for _, h := range s.list() {
+		status := WalletStatus{Chain: h.config.ChainId, Target: h.config.Submitter.ChainId}
+		accounts, err := h.Wallets()
+		if err != nil {
+			status.Error = err.Error()
+		}
+		status.Accounts = accounts
+		list = append(list, status)
+	}
+	reply(w, http.StatusOK, list)
+}
+
+func (s *HttpServer) Pause(w http.ResponseWriter, r *http.Request, h *HeaderSyncHandler) {
+	h.Pause()
+	reply(w, http.StatusOK, h.Status())
+}
+
+func (s *HttpServer) Resume(w http.ResponseWriter, r *http.Request, h *HeaderSyncHandler) {
+	h.Resume()
+	reply(w, http.StatusOK, h.Status())
+}
+
+func (s *HttpServer) Resync(w http.ResponseWriter, r *http.Request, h *HeaderSyncHandler) {
+	height, err := strconv.ParseUint(r.FormValue("height"), 10, 64)
+	if err == nil {
+		err = h.Resync(height)
+	}
+	if err != nil {
+		replyError(w, http.StatusBadRequest, err)
+		return
+	}
+	log.Warn("Header sync resync requested", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId, "height", height)
+	reply(w, http.StatusOK, h.Status())
+}
+
+// Wrap admin actions with token check, the target direction is selected with chain and target params
+func (s *HttpServer) admin(f func(http.ResponseWriter, *http.Request, *HeaderSyncHandler)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			replyError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed"))
+			return
+		}
+		if s.token == "" {
+			replyError(w, http.StatusForbidden, fmt.Errorf("Admin actions are disabled"))
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			replyError(w, http.StatusUnauthorized, fmt.Errorf("Invalid admin token"))
+			return
+		}
+		chain, err := strconv.ParseUint(r.FormValue("chain"), 10, 64)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, fmt.Errorf("Invalid chain %v", err))
+			return
+		}
+		target, err := strconv.ParseUint(r.FormValue("target"), 10, 64)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, fmt.Errorf("Invalid target %v", err))
+			return
+		}
+		for _, h := range s.list() {
+			if h.config.ChainId == chain && h.config.Submitter.ChainId == target {
+				f(w, r, h)
+				return
+			}
+		}
+		replyError(w, http.StatusNotFound, fmt.Errorf("No header sync from chain %d to %d", chain, target))
+	}
+}
+
+func reply(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func replyError(w http.ResponseWriter, code int, err error) {
+	reply(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,78 @@
+package relayer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/top/top-relayer/msg"
+)
+
+func adminRequest(s *HttpServer, method, path, token, form string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHttpAdminAuth(t *testing.T) {
+	h := newTestHandler(t, &fakeListener{}, &fakeSubmitter{}, 0)
+	s := NewHttpServer("localhost", 0, "secret", []Handler{h})
+	direction := "chain=1&target=0"
+
+	cases := []struct {
+		name   string
+		method string
+		token  string
+		form   string
+		code   int
+	}{
+		{"get method", http.MethodGet, "secret", direction, http.StatusMethodNotAllowed},
+		{"missing token", http.MethodPost, "", direction, http.StatusUnauthorized},
+		{"invalid token", http.MethodPost, "wrong", direction, http.StatusUnauthorized},
+		{"invalid chain", http.MethodPost, "secret", "chain=x&target=0", http.StatusBadRequest},
+		{"unknown direction", http.MethodPost, "secret", "chain=0&target=1", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if w := adminRequest(s, c.method, "/api/v1/pause", c.token, c.form); w.Code != c.code {
+			t.Fatalf("%s: expect status %d, got %d %s", c.name, c.code, w.Code, w.Body)
+		}
+	}
+	if h.Paused() {
+		t.Fatal("expect rejected requests not to pause the header sync")
+	}
+
+	if w := adminRequest(s, http.MethodPost, "/api/v1/pause", "secret", direction); w.Code != http.StatusOK || !h.Paused() {
+		t.Fatalf("expect header sync paused, got %d %s", w.Code, w.Body)
+	}
+	if w := adminRequest(s, http.MethodPost, "/api/v1/resume", "secret", direction); w.Code != http.StatusOK || h.Paused() {
+		t.Fatalf("expect header sync resumed, got %d %s", w.Code, w.Body)
+	}
+	if w := adminRequest(s, http.MethodPost, "/api/v1/resync", "secret", direction+"&height=0"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expect invalid resync height rejected, got %d", w.Code)
+	}
+	if w := adminRequest(s, http.MethodPost, "/api/v1/resync", "secret", direction+"&height=100"); w.Code != http.StatusOK {
+		t.Fatalf("expect resync accepted, got %d %s", w.Code, w.Body)
+	}
+	if reset := <-h.reset; reset != (msg.Reset{Height: 100, Force: true}) {
+		t.Fatalf("expect forced reset to 100, got %+v", reset)
+	}
+
+	// Status is served without token
+	if w := adminRequest(s, http.MethodGet, "/api/v1/status", "", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Chain":1`) {
+		t.Fatalf("expect status served, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestHttpAdminDisabled(t *testing.T) {
+	h := newTestHandler(t, &fakeListener{}, &fakeSubmitter{}, 0)
+	s := NewHttpServer("localhost", 0, "", []Handler{h})
+	if w := adminRequest(s, http.MethodPost, "/api/v1/pause", "", "chain=1&target=0"); w.Code != http.StatusForbidden || h.Paused() {
+		t.Fatalf("expect admin actions disabled without token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,121 @@
+package live
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	ethcommon "github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/config"
+)
+
+// Settings of a header sync direction which are applied in place on reload
+type Settings struct {
+	Batch         int
+	Timeout       int
+	ListenCheck   int
+	LagAlert      int
+	MaxReorgDepth int
+	StopTimeout   int
+}
+
+// FromConfig returns the reloadable settings of the header sync config
+func FromConfig(c *config.HeaderSyncConfig) (s Settings) {
+	s = Settings{
+		Batch: c.Batch, Timeout: c.Timeout,
+		LagAlert: c.LagAlert, MaxReorgDepth: c.MaxReorgDepth, StopTimeout: c.StopTimeout,
+	}
+	if c.ListenerConfig != nil {
+		s.ListenCheck = c.ListenCheck
+	}
+	return
+}
+
+// Merge the new settings, unset batch and timeout keep the current values
+func (s Settings) Merge(o Settings) Settings {
+	if o.Batch <= 0 {
+		o.Batch = s.Batch
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = s.Timeout
+	}
+	return o
+}
+
+// Value holds a settings snapshot which is replaced as a whole
+type Value struct {
+	mu       sync.RWMutex
+	settings Settings
+}
+
+func (v *Value) Load() Settings {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.settings
+}
+
+func (v *Value) Store(s Settings) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.settings = s
+}
+
+// Conn keeps the node sdk, the peer sdk and the settings of a running listener or submitter,
+// reload swaps them under the lock so that the sync goroutines always read a consistent snapshot.
+type Conn struct {
+	Value
+	chain uint64
+	mu    sync.RWMutex
+	nodes []string
+	sdk   *ethcommon.SDK
+	peer  *ethcommon.SDK
+}
+
+func Dial(chain uint64, nodes []string, settings Settings) (c *Conn, err error) {
+	sdk, err := ethcommon.WithOptions(chain, nodes, time.Minute, 1)
+	if err != nil {
+		return
+	}
+	c = &Conn{chain: chain, nodes: nodes, sdk: sdk}
+	c.Store(settings)
+	return
+}
+
+func (c *Conn) SDK() *ethcommon.SDK {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sdk
+}
+
+// Peer sdk of the target chain, nil if not provided
+func (c *Conn) Peer() *ethcommon.SDK {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.peer
+}
+
+func (c *Conn) SetPeer(peer *ethcommon.SDK) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peer = peer
+}
+
+// Reload connects to the node list if it changed and merges the settings
+func (c *Conn) Reload(nodes []string, settings Settings) (err error) {
+	c.mu.RLock()
+	changed := !reflect.DeepEqual(nodes, c.nodes)
+	c.mu.RUnlock()
+	if changed {
+		sdk, err := ethcommon.WithOptions(c.chain, nodes, time.Minute, 1)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.sdk, c.nodes = sdk, nodes
+		c.mu.Unlock()
+		log.Info("Reloaded nodes", "chain", c.chain, "nodes", nodes)
+	}
+	c.Store(c.Load().Merge(settings))
+	return
+}
@@ -0,0 +1,50 @@
+package relayer
+
+import (
+	"sync"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/relayer/eth"
+	"github.com/top/top-relayer/relayer/top"
+)
+
+// Family creates the listener and submitter for chains of the family
+type Family struct {
+	Listener  func() IChainListener
+	Submitter func() IChainSubmitter
+}
+
+var (
+	families     = map[string]Family{}
+	familiesLock sync.RWMutex
+)
+
+func init() {
+	RegisterFamily(base.FAMILY_TOP, Family{
+		Listener:  func() IChainListener { return new(top.Listener) },
+		Submitter: func() IChainSubmitter { return new(top.Submitter) },
+	})
+	RegisterFamily(base.FAMILY_ETH, evm(eth.EncodeJSON))
+	RegisterFamily(base.FAMILY_PARLIA, evm(eth.EncodeRLP))
+}
+
+// evm family differing only in the header encoding verified by the light client
+func evm(encode eth.HeaderEncoder) Family {
+	return Family{
+		Listener:  func() IChainListener { return eth.NewListener(encode) },
+		Submitter: func() IChainSubmitter { return new(eth.Submitter) },
+	}
+}
+
+func RegisterFamily(name string, family Family) {
+	familiesLock.Lock()
+	defer familiesLock.Unlock()
+	families[name] = family
+}
+
+func GetFamily(name string) (family Family, ok bool) {
+	familiesLock.RLock()
+	defer familiesLock.RUnlock()
+	family, ok = families[name]
+	return
+}
@@ -0,0 +1,53 @@
+package relayer
+
+import (
+	"testing"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/relayer/eth"
+	"github.com/top/top-relayer/relayer/top"
+)
+
+func TestChainRegistry(t *testing.T) {
+	t.Cleanup(func() { base.SetNetwork(base.MAINNET) })
+	const polygon, unknown = 137, 999
+	base.Register(base.Chain{Id: polygon, Name: "polygon", Family: base.FAMILY_ETH, BlocksToWait: 128})
+	base.Register(base.Chain{Id: unknown, Name: "unknown", Family: "cosmos"})
+
+	if _, ok := GetSubmitter(polygon).(*eth.Submitter); !ok {
+		t.Fatal("expect eth submitter for the registered evm chain")
+	}
+	if GetListener(polygon) == nil || base.GetChainName(polygon) != "polygon" || base.BlocksToWait(polygon) != 128 {
+		t.Fatal("expect the registered chain served by the eth family")
+	}
+	if base.BlocksToSkip(polygon) != 1 {
+		t.Fatalf("expect default blocks to skip, got %d", base.BlocksToSkip(polygon))
+	}
+	if _, ok := GetSubmitter(base.TOP).(*top.Submitter); !ok {
+		t.Fatal("expect top submitter for top chain")
+	}
+	if GetListener(unknown) != nil || GetSubmitter(unknown) != nil {
+		t.Fatal("expect no listener or submitter for unregistered family")
+	}
+	if GetListener(1000) != nil {
+		t.Fatal("expect no listener for unregistered chain")
+	}
+
+	// Families registered later serve the chains too
+	RegisterFamily("cosmos", Family{
+		Listener:  func() IChainListener { return &fakeListener{} },
+		Submitter: func() IChainSubmitter { return &fakeSubmitter{} },
+	})
+	t.Cleanup(func() {
+		familiesLock.Lock()
+		delete(families, "cosmos")
+		familiesLock.Unlock()
+	})
+	if _, ok := GetListener(unknown).(*fakeListener); !ok {
+		t.Fatal("expect listener of the registered family")
+	}
+
+	if ids := base.Chains(); ids[len(ids)-1] != unknown || ids[0] != base.TOP {
+		t.Fatalf("expect registered chains in order, got %v", ids)
+	}
+}
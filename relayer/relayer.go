@@ -11,8 +11,8 @@ import (
 	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
 	"github.com/top/top-relayer/msg"
-	"github.com/top/top-relayer/relayer/eth"
-	"github.com/top/top-relayer/relayer/top"
+	"github.com/top/top-relayer/relayer/sender"
+	"github.com/top/top-relayer/store"
 )
 
 type IChainListener interface {
@@ -21,7 +21,7 @@ type IChainListener interface {
 	ListenCheck() time.Duration
 	ChainId() uint64
 	Nodes() chains.Nodes
-	Header(height uint64) (header []byte, hash []byte, err error)
+	Header(height uint64) (header *msg.Header, err error)
 	LastHeaderSync(uint64, uint64) (uint64, error)
 	LatestHeight() (uint64, error)
 }
@@ -40,27 +40,61 @@ type IChainSubmitter interface {
 	SDK() *ethcommon.SDK
 	GetSideChainHeader(chainId, height uint64) (hash []byte, err error)
 	GetSideChainHeight(chainId uint64) (height uint64, err error)
-	StartSync(ctx context.Context, wg *sync.WaitGroup, reset chan<- uint64) (ch chan msg.Header, err error)
+	Wallets() ([]sender.Status, error)
+	StartSync(ctx context.Context, wg *sync.WaitGroup, reset chan<- msg.Reset, state *store.State) (ch chan msg.Header, err error)
+}
+
+// IForkTracker is implemented by submitters whose light client contract tracks block hashes with events
+type IForkTracker interface {
+	// Block hashes reverted and added by the light client contract in recent blocks of target chain
+	BlockHashEvents() (reverted, added []msg.BlockHash, err error)
+	// Height of the block hash in light client, zero if not exist
+	GetHeightByHash(hash []byte) (height uint64, err error)
+}
+
+// IGenesisInitializer is implemented by submitters whose light client is bootstrapped with a genesis header
+type IGenesisInitializer interface {
+	InitGenesis(ctx context.Context, header *msg.Header, emitter string) (hash string, err error)
+}
+
+// IBridgeBonder is implemented by submitters of light client contracts requiring relayers to deposit a bond
+type IBridgeBonder interface {
+	Deposit(ctx context.Context) (err error)
+}
+
+// IPauseWatcher is implemented by submitters suspending submission while the light client contract is paused
+type IPauseWatcher interface {
+	// Pause flags of the last poll if submission is suspended, otherwise zero
+	Suspended() (flags uint64)
+}
+
+// IPauseReader is implemented by listeners able to read the pause flags of the light client contract on the target chain
+type IPauseReader interface {
+	PauseFlags() (flags uint64, err error)
+}
+
+// IListenerReloader is implemented by listeners that apply config changes in place
+type IListenerReloader interface {
+	Reload(*config.HeaderSyncConfig, *ethcommon.SDK) error
+}
+
+// ISubmitterReloader is implemented by submitters that apply config changes in place
+type ISubmitterReloader interface {
+	Reload(*config.HeaderSyncConfig) error
 }
 
 func GetListener(chain uint64) (listener IChainListener) {
-	switch chain {
-	case base.ETH, base.BSC:
-		listener = new(eth.Listener)
-	case base.TOP:
-		listener = new(top.Listener)
-	default:
+	family, ok := GetFamily(base.Family(chain))
+	if ok {
+		listener = family.Listener()
 	}
 	return
 }
 
 func GetSubmitter(chain uint64) (submitter IChainSubmitter) {
-	switch chain {
-	case base.ETH, base.BSC:
-		submitter = new(eth.Submitter)
-	case base.TOP:
-		submitter = new(top.Submitter)
-	default:
+	family, ok := GetFamily(base.Family(chain))
+	if ok {
+		submitter = family.Submitter()
 	}
 	return
 }
@@ -0,0 +1,164 @@
+package relayer
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/relayer/live"
+)
+
+// Header sync direction of a handler
+type direction struct {
+	chain  uint64
+	target uint64
+}
+
+// Reloadable checks if the changes from the running config can be applied in place.
+// Node lists, batch, timeout, gas and alerting thresholds are reloadable, other changes require a restart of the direction.
+// Buffer size changes are ignored, the header channel keeps its size until the direction is restarted.
+func Reloadable(old, conf *config.HeaderSyncConfig) bool {
+	if (old.Batch <= 1) != (conf.Batch <= 1) {
+		// Switching between single and batch submit loops
+		return false
+	}
+	strip := func(c *config.HeaderSyncConfig) config.HeaderSyncConfig {
+		o := *c
+		o.Batch, o.Buffer, o.Timeout, o.LagAlert, o.MaxReorgDepth, o.StopTimeout = 0, 0, 0, 0, 0, 0
+		if c.ListenerConfig != nil {
+			listener := *c.ListenerConfig
+			listener.Nodes, listener.ExtraNodes, listener.ListenCheck = nil, nil, 0
+			o.ListenerConfig = &listener
+		}
+		if c.Submitter != nil {
+			submitter := *c.Submitter
+			submitter.Nodes, submitter.ExtraNodes, submitter.Gas = nil, nil, nil
+			o.Submitter = &submitter
+		}
+		return o
+	}
+	return reflect.DeepEqual(strip(old), strip(conf))
+}
+
+// Reload applies the reloadable config changes in place
+func (h *HeaderSyncHandler) Reload(conf *config.HeaderSyncConfig) (err error) {
+	if !Reloadable(h.config, conf) {
+		return fmt.Errorf("Header sync config changes can not be applied in place")
+	}
+	if submitter, ok := h.submitter.(ISubmitterReloader); ok {
+		err = submitter.Reload(conf)
+		if err != nil {
+			return
+		}
+	}
+	if listener, ok := h.listener.(IListenerReloader); ok {
+		err = listener.Reload(conf, h.submitter.SDK())
+		if err != nil {
+			return
+		}
+	}
+	h.settings.Store(h.settings.Load().Merge(live.FromConfig(conf)))
+	h.config = conf
+	log.Info("Reloaded header sync config", "chain", h.config.ChainId, "target", h.config.Submitter.ChainId)
+	return
+}
+
+// Reload diffs the new config against the running roles: directions removed are stopped, new directions are started,
+// and changed directions are reloaded in place or restarted from the last synced height.
+func (s *Server) Reload(c *config.Config) (err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if c.Host != s.config.Host || c.Port != s.config.Port || c.AdminToken != s.config.AdminToken ||
+		c.StorePath != s.config.StorePath || !reflect.DeepEqual(c.Alert, s.config.Alert) {
+		log.Warn("Http, store and alert config changes are ignored until restart")
+	}
+
+	confs := map[direction]*config.HeaderSyncConfig{}
+	order := []direction{}
+	for id, chain := range c.Chains {
+		if !c.Active(id) {
+			continue
+		}
+		for _, conf := range chain.HeaderSync {
+			if conf == nil || !conf.Enabled || conf.Submitter == nil {
+				continue
+			}
+			key := direction{conf.ChainId, conf.Submitter.ChainId}
+			confs[key] = conf
+			order = append(order, key)
+		}
+	}
+
+	failures := 0
+	roles := []Handler{}
+	running := map[direction]bool{}
+	for _, role := range s.roles {
+		h, ok := role.(*HeaderSyncHandler)
+		if !ok {
+			roles = append(roles, role)
+			continue
+		}
+		key := direction{h.config.ChainId, h.config.Submitter.ChainId}
+		conf, ok := confs[key]
+		if !ok {
+			log.Info("Stopping removed header sync", "chain", key.chain, "target", key.target)
+			h.Stop()
+			continue
+		}
+		running[key] = true
+		if Reloadable(h.config, conf) {
+			e := h.Reload(conf)
+			if e == nil {
+				roles = append(roles, h)
+				continue
+			}
+			log.Error("Failed to reload header sync in place, will restart it", "chain", key.chain, "target", key.target, "err", e)
+		}
+		log.Info("Restarting changed header sync", "chain", key.chain, "target", key.target)
+		h.Stop()
+		if handler := s.startHandler(key.chain, conf); handler != nil {
+			roles = append(roles, handler)
+		} else {
+			failures++
+		}
+	}
+	for _, key := range order {
+		if running[key] {
+			continue
+		}
+		log.Info("Starting new header sync", "chain", key.chain, "target", key.target)
+		if handler := s.startHandler(key.chain, confs[key]); handler != nil {
+			roles = append(roles, handler)
+		} else {
+			failures++
+		}
+	}
+
+	s.roles = roles
+	s.config.ApplyChains(c)
+	s.http.SetRoles(roles)
+	if failures > 0 {
+		err = fmt.Errorf("Failed to start %d header sync directions", failures)
+	}
+	return
+}
+
+// Create, initialize and start the handler, returns nil on failure
+func (s *Server) startHandler(chain uint64, conf *config.HeaderSyncConfig) Handler {
+	handler := s.parseHandler(chain, conf)
+	if handler == nil {
+		return nil
+	}
+	err := handler.Init(s.ctx, s.wg)
+	if err == nil {
+		err = handler.Start()
+	}
+	if err != nil {
+		log.Error("Failed to start role", "type", reflect.TypeOf(handler), "chain", chain, "err", err)
+		handler.Stop()
+		return nil
+	}
+	return handler
+}
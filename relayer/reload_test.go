@@ -0,0 +1,88 @@
+package relayer
+
+import (
+	"testing"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+)
+
+func reloadConfig(change func(c *config.HeaderSyncConfig)) *config.HeaderSyncConfig {
+	c := &config.HeaderSyncConfig{
+		Batch:   4,
+		Enabled: true,
+		Submitter: &config.SubmitterConfig{
+			ChainId: base.TOP, Nodes: []string{"http://a"}, HSContract: "0x01",
+			Gas: &config.GasConfig{Strategy: "suggested"},
+		},
+		ListenerConfig: &config.ListenerConfig{ChainId: base.ETH, Nodes: []string{"http://b"}, ListenCheck: 3},
+	}
+	if change != nil {
+		change(c)
+	}
+	return c
+}
+
+func TestReloadable(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(c *config.HeaderSyncConfig)
+		ok     bool
+	}{
+		{"unchanged", nil, true},
+		{"batch size", func(c *config.HeaderSyncConfig) { c.Batch = 8 }, true},
+		{"batch to single", func(c *config.HeaderSyncConfig) { c.Batch = 1 }, false},
+		{"buffer", func(c *config.HeaderSyncConfig) { c.Buffer = 16 }, true},
+		{"timeout and thresholds", func(c *config.HeaderSyncConfig) {
+			c.Timeout, c.LagAlert, c.MaxReorgDepth, c.StopTimeout = 3, 10, 20, 30
+		}, true},
+		{"listener nodes", func(c *config.HeaderSyncConfig) { c.ListenerConfig.Nodes, c.ListenCheck = []string{"http://c"}, 5 }, true},
+		{"submitter nodes and gas", func(c *config.HeaderSyncConfig) {
+			c.Submitter.Nodes, c.Submitter.Gas = []string{"http://d"}, &config.GasConfig{Strategy: "eip1559"}
+		}, true},
+		{"contract", func(c *config.HeaderSyncConfig) { c.Submitter.HSContract = "0x02" }, false},
+		{"header window", func(c *config.HeaderSyncConfig) { c.HeaderWindow = 100 }, false},
+		{"listener defer", func(c *config.HeaderSyncConfig) { c.Defer = 2 }, false},
+	}
+	for _, c := range cases {
+		if ok := Reloadable(reloadConfig(nil), reloadConfig(c.change)); ok != c.ok {
+			t.Fatalf("%s: expect reloadable %v, got %v", c.name, c.ok, ok)
+		}
+	}
+}
+
+// Submitter applying the reloaded configs
+type reloadSubmitter struct {
+	*fakeSubmitter
+	reloaded []*config.HeaderSyncConfig
+}
+
+func (s *reloadSubmitter) Reload(conf *config.HeaderSyncConfig) error {
+	s.reloaded = append(s.reloaded, conf)
+	return nil
+}
+
+func TestHeaderSyncReload(t *testing.T) {
+	submitter := &reloadSubmitter{fakeSubmitter: &fakeSubmitter{}}
+	h := newTestHandler(t, &fakeListener{}, submitter, 0)
+	h.config = reloadConfig(nil)
+
+	conf := reloadConfig(func(c *config.HeaderSyncConfig) { c.Batch, c.MaxReorgDepth = 8, 20 })
+	if err := h.Reload(conf); err != nil {
+		t.Fatal(err)
+	}
+	if h.config != conf || len(submitter.reloaded) != 1 || submitter.reloaded[0] != conf {
+		t.Fatal("expect the reloaded config applied to the handler and submitter")
+	}
+	if settings := h.settings.Load(); settings.MaxReorgDepth != 20 {
+		t.Fatalf("expect max reorg depth 20, got %d", settings.MaxReorgDepth)
+	}
+
+	changed := reloadConfig(func(c *config.HeaderSyncConfig) { c.Submitter.HSContract = "0x02" })
+	if err := h.Reload(changed); err == nil {
+		t.Fatal("expect contract change not reloadable")
+	}
+	if h.config != conf || len(submitter.reloaded) != 1 {
+		t.Fatal("expect running config kept on failed reload")
+	}
+}
@@ -0,0 +1,313 @@
+package relayer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/msg"
+)
+
+const (
+	MAX_REORG_DEPTH   = 1000 // Default max blocks to roll back when searching the common ancestor
+	REORG_REPORT_SIZE = 16   // Max blocks above the common ancestor listed in reorg report
+)
+
+// Reorg report of a header sync rollback
+type Reorg struct {
+	Chain    uint64
+	Target   uint64
+	Height   uint64          // height of the failure triggered the rollback
+	Ancestor uint64          // common ancestor height
+	Depth    uint64          // blocks rolled back below the failure height
+	Old      []msg.BlockHash // light client branch above the ancestor
+	New      []msg.BlockHash // source chain branch above the ancestor
+	Reverted []msg.BlockHash `json:",omitempty"` // block hashes reverted by the light client contract
+	Time     time.Time
+	Error    string `json:",omitempty"`
+}
+
+// Block hashes fetched during the common ancestor search
+type reorgProbe struct {
+	source map[uint64][]byte
+	target map[uint64][]byte
+}
+
+// Seed the light client hashes with the block hashes added in (floor, top] and not reverted,
+// heights with more than one hash added are skipped as their order is unknown. Returns the seeded heights in order.
+func (p *reorgProbe) seed(added, reverted []msg.BlockHash, floor, top uint64) (heights []uint64) {
+	dropped := map[msg.BlockHash]bool{}
+	for _, b := range reverted {
+		dropped[b] = true
+	}
+	hashes := map[uint64][][]byte{}
+	for _, b := range added {
+		if b.Height <= floor || b.Height > top || dropped[b] {
+			continue
+		}
+		hash, err := hex.DecodeString(strings.TrimPrefix(b.Hash, "0x"))
+		if err != nil {
+			continue
+		}
+		hashes[b.Height] = append(hashes[b.Height], hash)
+	}
+	for height, list := range hashes {
+		if len(list) == 1 {
+			p.target[height] = list[0]
+			heights = append(heights, height)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return
+}
+
+func (h *HeaderSyncHandler) maxReorgDepth() uint64 {
+	if depth := h.settings.Load().MaxReorgDepth; depth > 0 {
+		return uint64(depth)
+	}
+	return MAX_REORG_DEPTH
+}
+
+// RollbackToCommonAncestor searches the highest block below target that the light client agrees with the source chain.
+// Candidates are stepped back exponentially from target, then narrowed down with binary search. Block hash events of
+// light client contracts are used when available: reverted hashes hint the start, and the heights added by the light client
+// are binary searched first to bound the window. If no common ancestor is found within the max reorg depth,
+// header sync is paused and the target is returned unchanged.
+func (h *HeaderSyncHandler) RollbackToCommonAncestor(failed, target uint64) uint64 {
+	log.Warn("Rolling header sync back to common ancestor", "failed", failed, "goal", target, "chain", h.config.ChainId)
+	report := &Reorg{Chain: h.config.ChainId, Target: h.config.Submitter.ChainId, Height: failed, Time: time.Now()}
+	probe := &reorgProbe{source: map[uint64][]byte{}, target: map[uint64][]byte{}}
+
+	var floor uint64
+	if depth := h.maxReorgDepth(); target > depth {
+		floor = target - depth
+	}
+
+	hint := target
+	var added []uint64
+	if tracker, ok := h.submitter.(IForkTracker); ok {
+		reverted, events, err := tracker.BlockHashEvents()
+		if err != nil {
+			log.Warn("Failed to fetch light client block hash events", "chain", h.config.ChainId, "err", err)
+		}
+		for _, b := range reverted {
+			if b.Height <= floor || b.Height > failed {
+				continue
+			}
+			report.Reverted = append(report.Reverted, b)
+			if b.Height-1 < hint {
+				hint = b.Height - 1
+			}
+		}
+		added = probe.seed(events, reverted, floor, hint)
+	}
+
+	var (
+		ancestor uint64
+		found    bool
+		hi       = target + 1 // lowest height known to be inconsistent
+	)
+	// Binary search the heights added by the light client first, their hashes are known without rpc calls
+	for lo, up := 0, len(added)-1; lo <= up; {
+		mid := lo + (up-lo)/2
+		ok, err := h.consistent(added[mid], probe)
+		if err != nil {
+			return target
+		}
+		if ok {
+			ancestor, found = added[mid], true
+			lo = mid + 1
+		} else {
+			hi = added[mid]
+			up = mid - 1
+		}
+	}
+	if hi <= hint {
+		hint = hi - 1
+	}
+
+	height, step := hint, uint64(1)
+	for !found {
+		ok, err := h.consistent(height, probe)
+		if err != nil {
+			// Exiting
+			return target
+		}
+		if ok {
+			ancestor, found = height, true
+			break
+		}
+		hi = height
+		if height <= floor {
+			break
+		}
+		if height-floor > step {
+			height -= step
+		} else {
+			height = floor
+		}
+		step *= 2
+	}
+
+	if !found {
+		report.Error = fmt.Sprintf("No common ancestor found within %d blocks", h.maxReorgDepth())
+		h.emitReorg(report, probe)
+		log.Error("Reorg is deeper than max depth, header sync paused for manual resync", "chain", h.config.ChainId, "floor", floor)
+		h.Pause()
+		return target
+	}
+
+	for hi-ancestor > 1 {
+		mid := ancestor + (hi-ancestor)/2
+		ok, err := h.consistent(mid, probe)
+		if err != nil {
+			return target
+		}
+		if ok {
+			ancestor = mid
+		} else {
+			hi = mid
+		}
+	}
+	log.Info("Found common ancestor", "chain", h.config.ChainId, "height", ancestor)
+
+	report.Ancestor = ancestor
+	if failed > ancestor+1 {
+		report.Depth = failed - ancestor - 1
+	}
+	if report.Depth > 0 || len(report.Reverted) > 0 {
+		h.emitReorg(report, probe)
+	}
+
+	return h.epochStart(ancestor)
+}
+
+// Move the ancestor back below the epoch start, so the validator set will be applied again on resume
+func (h *HeaderSyncHandler) epochStart(ancestor uint64) uint64 {
+	if epoch := base.Epoch(h.config.ChainId); epoch > 0 && ancestor > epoch {
+		ancestor = ancestor - ancestor%epoch - 1
+		log.Info("Rollback header sync to epoch start", "chain", h.config.ChainId, "height", ancestor+1)
+	}
+	return ancestor
+}
+
+// Check if the light client block hash at the height is the same as the source chain,
+// rpc calls are retried until the handler exits.
+func (h *HeaderSyncHandler) consistent(height uint64, probe *reorgProbe) (ok bool, err error) {
+	var (
+		a      []byte
+		header *msg.Header
+	)
+	err = h.retry(func() (err error) {
+		header, err = h.listener.Header(height)
+		return
+	})
+	if err != nil {
+		return
+	}
+	a = header.Hash
+	probe.source[height] = a
+
+	b, cached := probe.target[height]
+	if !cached {
+		err = h.retry(func() (err error) {
+			b, err = h.submitter.GetSideChainHeader(h.config.ChainId, height)
+			return
+		})
+		if err != nil {
+			return
+		}
+	}
+	if isEmptyHash(b) {
+		// Fall back to the locally recorded hash of submitted headers
+		b, _ = h.state.Hash(height)
+	}
+	if tracker, isTracker := h.submitter.(IForkTracker); isTracker && isEmptyHash(b) {
+		var res uint64
+		err = h.retry(func() (err error) {
+			res, err = tracker.GetHeightByHash(a)
+			return
+		})
+		if err != nil {
+			return
+		}
+		if res == height {
+			b = a
+		}
+	}
+	probe.target[height] = b
+	ok = bytes.Equal(a, b)
+	log.Debug("Checked header consistency", "chain", h.config.ChainId, "height", height, "consistent", ok)
+	return
+}
+
+// Retry with backoff until success or the handler exits
+func (h *HeaderSyncHandler) retry(f func() error) (err error) {
+	backoff := time.Second
+	for {
+		err = f()
+		if err == nil {
+			return
+		}
+		log.Error("Header consistency check error, will retry", "chain", h.config.ChainId, "err", err)
+		select {
+		case <-h.Done():
+			return h.Err()
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (h *HeaderSyncHandler) emitReorg(report *Reorg, probe *reorgProbe) {
+	for height := report.Ancestor + 1; report.Error == "" && height <= report.Height && height <= report.Ancestor+REORG_REPORT_SIZE; height++ {
+		a, ok := probe.source[height]
+		if !ok {
+			if header, err := h.listener.Header(height); err == nil {
+				a = header.Hash
+			}
+		}
+		b, ok := probe.target[height]
+		if !ok {
+			b, _ = h.submitter.GetSideChainHeader(h.config.ChainId, height)
+		}
+		if !isEmptyHash(b) {
+			report.Old = append(report.Old, msg.BlockHash{Height: height, Hash: "0x" + hex.EncodeToString(b)})
+		}
+		if len(a) > 0 {
+			report.New = append(report.New, msg.BlockHash{Height: height, Hash: "0x" + hex.EncodeToString(a)})
+		}
+	}
+	h.reportReorg(report)
+}
+
+func (h *HeaderSyncHandler) reportReorg(report *Reorg) {
+	h.metrics.Reorgs.Inc(1)
+	h.metrics.ReorgDepth.Update(int64(report.Depth))
+	h.lastReorg.Store(report)
+	data, _ := json.Marshal(report)
+	log.Warn("Header sync reorg detected", "chain", h.config.ChainId, "ancestor", report.Ancestor, "depth", report.Depth, "report", string(data))
+	if report.Error != "" {
+		h.raise(alert.CRITICAL, "reorg", "Reorg beyond max depth", "%s, failed at height %d", report.Error, report.Height)
+	} else {
+		h.raise(alert.WARN, "reorg", "Reorg detected", "Rolled back %d blocks to common ancestor %d", report.Depth, report.Ancestor)
+	}
+}
+
+func isEmptyHash(hash []byte) bool {
+	for _, b := range hash {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
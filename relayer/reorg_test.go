@@ -0,0 +1,143 @@
+package relayer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
+)
+
+// Light client tracking block hashes with events
+type fakeTracker struct {
+	*fakeSubmitter
+	added, reverted []msg.BlockHash
+}
+
+func (s *fakeTracker) BlockHashEvents() (reverted, added []msg.BlockHash, err error) {
+	return s.reverted, s.added, nil
+}
+
+func (s *fakeTracker) GetHeightByHash(hash []byte) (height uint64, err error) {
+	return
+}
+
+func hashEvents(hashes map[uint64][]byte, from, to uint64) (events []msg.BlockHash) {
+	for height := from; height <= to; height++ {
+		if hash, ok := hashes[height]; ok {
+			events = append(events, msg.BlockHash{Height: height, Hash: "0x" + hex.EncodeToString(hash)})
+		}
+	}
+	return
+}
+
+func TestRollbackToCommonAncestor(t *testing.T) {
+	const target = 20
+	cases := []struct {
+		name     string
+		fork     uint64   // height the source chain forked from the light client branch
+		depth    int      // max reorg depth
+		missing  []uint64 // heights without hash in light client, inconsistent below the fork
+		ancestor uint64
+		paused   bool
+	}{
+		{"ancestor at genesis", 0, 0, nil, 0, false},
+		{"ancestor at tip", target, 0, nil, target, false},
+		{"ancestor in middle", 11, 0, nil, 11, false},
+		{"deeper than max depth", 5, 8, nil, target, true},
+		{"non monotonic", 15, 0, []uint64{12}, 15, false},
+		{"holes far below ancestor", 15, 0, []uint64{3, 4}, 15, false},
+	}
+	for _, c := range cases {
+		for _, tracked := range []bool{false, true} {
+			name := c.name
+			if tracked {
+				name += " with tracker"
+			}
+			t.Run(name, func(t *testing.T) {
+				listener := &fakeListener{hashes: branchHashes(1, c.fork, target+1)}
+				lightClient := branchHashes(0, 0, target)
+				for _, height := range c.missing {
+					delete(lightClient, height)
+				}
+				submitter := &fakeSubmitter{hashes: lightClient}
+				h := newTestHandler(t, listener, submitter, 0)
+				if tracked {
+					h.submitter = &fakeTracker{fakeSubmitter: submitter, added: hashEvents(lightClient, 1, target)}
+				}
+				if c.depth > 0 {
+					h.settings.Store(live.Settings{MaxReorgDepth: c.depth})
+				}
+
+				ancestor := h.RollbackToCommonAncestor(target+1, target)
+				if ancestor != c.ancestor {
+					t.Fatalf("expect ancestor %d, got %d", c.ancestor, ancestor)
+				}
+				if h.Paused() != c.paused {
+					t.Fatalf("expect paused %v, got %v", c.paused, h.Paused())
+				}
+				// Only the floor below the added events needs a light client query
+				if tracked && len(c.missing) == 0 && submitter.calls > 1 {
+					t.Fatalf("expect hashes of added events used without rpc calls, got %d calls", submitter.calls)
+				}
+				if c.paused {
+					return
+				}
+				// The result is the highest consistent height below the first inconsistent one probed
+				if ancestor < target && bytes.Equal(listener.hashes[ancestor+1], lightClient[ancestor+1]) {
+					t.Fatalf("expect height %d above ancestor inconsistent", ancestor+1)
+				}
+			})
+		}
+	}
+}
+
+func TestRollbackWithRevertedHint(t *testing.T) {
+	const target, fork = 20, 9
+	listener := &fakeListener{hashes: branchHashes(1, fork, target+1)}
+	lightClient := branchHashes(0, 0, target)
+	submitter := &fakeSubmitter{hashes: lightClient}
+	tracker := &fakeTracker{
+		fakeSubmitter: submitter,
+		added:         hashEvents(lightClient, 1, target),
+		reverted:      hashEvents(lightClient, fork+3, fork+3),
+	}
+	h := newTestHandler(t, listener, tracker, 0)
+	ancestor := h.RollbackToCommonAncestor(target+1, target)
+	if ancestor != fork {
+		t.Fatalf("expect ancestor %d, got %d", fork, ancestor)
+	}
+	report, _ := h.lastReorg.Load().(*Reorg)
+	if report == nil || len(report.Reverted) != 1 || report.Reverted[0].Height != fork+3 {
+		t.Fatalf("expect reverted hash in reorg report, got %+v", report)
+	}
+}
+
+func TestReorgProbeSeed(t *testing.T) {
+	hash := func(b byte) string { return "0x" + hex.EncodeToString([]byte{b}) }
+	added := []msg.BlockHash{
+		{Height: 1, Hash: hash(1)},
+		{Height: 3, Hash: hash(3)},
+		{Height: 4, Hash: hash(4)},
+		{Height: 4, Hash: hash(5)}, // ambiguous height
+		{Height: 5, Hash: hash(6)},
+		{Height: 6, Hash: hash(7)},
+		{Height: 9, Hash: hash(9)},
+	}
+	reverted := []msg.BlockHash{{Height: 5, Hash: hash(6)}}
+	probe := &reorgProbe{source: map[uint64][]byte{}, target: map[uint64][]byte{}}
+	heights := probe.seed(added, reverted, 1, 6)
+	expect := []uint64{3, 6}
+	if len(heights) != len(expect) {
+		t.Fatalf("expect heights %v, got %v", expect, heights)
+	}
+	for i := range expect {
+		if heights[i] != expect[i] {
+			t.Fatalf("expect heights %v, got %v", expect, heights)
+		}
+	}
+	if len(probe.target) != len(expect) {
+		t.Fatalf("expect %d target hashes seeded, got %d", len(expect), len(probe.target))
+	}
+}
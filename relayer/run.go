@@ -7,22 +7,39 @@ import (
 	"time"
 
 	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/store"
 )
 
 type Server struct {
+	sync.Mutex
 	ctx    context.Context
 	wg     *sync.WaitGroup
 	config *config.Config
 	roles  []Handler
+	store  *store.Store
+	http   *HttpServer
 }
 
-func Start(ctx context.Context, wg *sync.WaitGroup, config *config.Config) error {
-	server := &Server{ctx, wg, config, nil}
-	return server.Start()
+func Start(ctx context.Context, wg *sync.WaitGroup, config *config.Config) (server *Server, err error) {
+	server = &Server{ctx: ctx, wg: wg, config: config}
+	err = server.Start()
+	return
 }
 
 func (s *Server) Start() (err error) {
+	log.Info("Starting relayer", "network", base.Env())
+	s.store, err = store.Open(s.config.StorePath)
+	if err != nil {
+		return
+	}
+	err = alert.Init(s.ctx, s.wg, s.config.Alert)
+	if err != nil {
+		return
+	}
+
 	// Create handlers
 	for id, chain := range s.config.Chains {
 		if s.config.Active(id) {
@@ -47,9 +64,33 @@ func (s *Server) Start() (err error) {
 			return
 		}
 	}
+
+	// Start http server
+	s.http = NewHttpServer(s.config.Host, s.config.Port, s.config.AdminToken, s.roles)
+	s.http.Start(s.ctx, s.wg)
 	return
 }
 
+// Stop the roles in reverse order of starting, then close the store
+func (s *Server) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	for i := len(s.roles) - 1; i >= 0; i-- {
+		handler := s.roles[i]
+		log.Info("Stopping role", "index", i, "total", len(s.roles), "type", reflect.TypeOf(handler), "chain", handler.Chain())
+		err := handler.Stop()
+		if err != nil {
+			log.Error("Failed to stop role", "index", i, "chain", handler.Chain(), "err", err)
+		}
+	}
+	if s.store != nil {
+		err := s.store.Close()
+		if err != nil {
+			log.Error("Failed to close store", "err", err)
+		}
+	}
+}
+
 func (s *Server) parseHandlers(chain uint64, confs ...interface{}) {
 	for _, conf := range confs {
 		handler := s.parseHandler(chain, conf)
@@ -66,7 +107,7 @@ func (s *Server) parseHandler(chain uint64, conf interface{}) (handler Handler)
 
 	switch c := conf.(type) {
 	case *config.HeaderSyncConfig:
-		handler = NewHeaderSyncHandler(c)
+		handler = NewHeaderSyncHandler(c, s.store.State(c.ChainId, c.Submitter.ChainId))
 	default:
 		log.Error("Unknown config type", "conf", conf)
 	}
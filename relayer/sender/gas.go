@@ -0,0 +1,281 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/config"
+)
+
+// Gas strategies
+const (
+	GAS_FIXED      = "fixed"
+	GAS_SUGGESTED  = "suggested"
+	GAS_EIP1559    = "eip1559"
+	GAS_PERCENTILE = "percentile"
+)
+
+// Fees of a tx, legacy tx is used if GasPrice is set
+type Fees struct {
+	GasPrice *big.Int
+	TipCap   *big.Int
+	FeeCap   *big.Int
+}
+
+// Cap of the fees, gas price for legacy tx or fee cap for dynamic fee tx
+func (f Fees) Cap() *big.Int {
+	if f.GasPrice != nil {
+		return f.GasPrice
+	}
+	return f.FeeCap
+}
+
+// GasStrategy decides the fees and gas limit of txs
+type GasStrategy struct {
+	strategy    string
+	price       *big.Int
+	multiplier  float64
+	maxFee      *big.Int
+	maxTip      *big.Int
+	percentile  int
+	blocks      int
+	maxPrice    *big.Int
+	limitMargin float64
+	maxLimit    uint64
+	bumpTimeout time.Duration
+	bumpPercent int64
+	maxBumps    int
+}
+
+// NewGasStrategy creates the gas strategy of the config, legacy txs priced by the node suggested gas price are sent by default,
+// dynamic fee txs are only sent by the eip1559 and percentile strategies for chains with the london fork.
+func NewGasStrategy(c *config.GasConfig) (g *GasStrategy, err error) {
+	g = &GasStrategy{
+		strategy: GAS_SUGGESTED, multiplier: 1, percentile: 50, blocks: 20, limitMargin: 0.3,
+		bumpTimeout: time.Minute, bumpPercent: 20, maxBumps: 3,
+	}
+	if c == nil {
+		return
+	}
+	if c.Strategy != "" {
+		g.strategy = c.Strategy
+	}
+	if c.Multiplier > 0 {
+		g.multiplier = c.Multiplier
+	}
+	if c.Percentile > 0 {
+		g.percentile = c.Percentile
+	}
+	if c.Blocks > 0 {
+		g.blocks = c.Blocks
+	}
+	if c.LimitMargin > 0 {
+		g.limitMargin = c.LimitMargin
+	}
+	g.maxLimit = c.MaxLimit
+	if c.BumpTimeout > 0 {
+		g.bumpTimeout = time.Duration(c.BumpTimeout) * time.Second
+	}
+	if c.BumpPercent > 0 {
+		if c.BumpPercent < MIN_BUMP_PERCENT {
+			return nil, fmt.Errorf("Gas bump percent should be at least %d", MIN_BUMP_PERCENT)
+		}
+		g.bumpPercent = int64(c.BumpPercent)
+	}
+	if c.MaxBumps != 0 {
+		g.maxBumps = c.MaxBumps
+	}
+	for _, v := range []struct {
+		name  string
+		value string
+		dst   **big.Int
+	}{
+		{"Price", c.Price, &g.price},
+		{"MaxFee", c.MaxFee, &g.maxFee},
+		{"MaxTip", c.MaxTip, &g.maxTip},
+		{"MaxPrice", c.MaxPrice, &g.maxPrice},
+	} {
+		if v.value == "" {
+			continue
+		}
+		value, ok := new(big.Int).SetString(v.value, 10)
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("Invalid gas config %s: %s", v.name, v.value)
+		}
+		*v.dst = value
+	}
+
+	switch g.strategy {
+	case GAS_FIXED:
+		if g.price == nil {
+			return nil, fmt.Errorf("Gas price is required by fixed gas strategy")
+		}
+	case GAS_SUGGESTED, GAS_EIP1559:
+	case GAS_PERCENTILE:
+		if g.percentile > 100 {
+			return nil, fmt.Errorf("Invalid gas percentile %d", g.percentile)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown gas strategy %s", g.strategy)
+	}
+	return
+}
+
+// Fees of the next tx
+func (g *GasStrategy) Fees(ctx context.Context, node *eth.Client) (fees Fees, err error) {
+	switch g.strategy {
+	case GAS_FIXED:
+		fees.GasPrice = new(big.Int).Set(g.price)
+	case GAS_SUGGESTED:
+		price, err := node.SuggestGasPrice(ctx)
+		if err != nil {
+			return fees, fmt.Errorf("Get gas price error %v", err)
+		}
+		fees.GasPrice = mul(price, g.multiplier)
+	case GAS_EIP1559:
+		tip, err := node.SuggestGasTipCap(ctx)
+		if err != nil {
+			return fees, fmt.Errorf("Get gas tip error %v", err)
+		}
+		fees.TipCap = mul(tip, g.multiplier)
+		if g.maxFee != nil {
+			fees.FeeCap = new(big.Int).Set(g.maxFee)
+		} else {
+			price, err := node.SuggestGasPrice(ctx)
+			if err != nil {
+				return fees, fmt.Errorf("Get gas price error %v", err)
+			}
+			fees.FeeCap = mul(price, 3)
+		}
+	case GAS_PERCENTILE:
+		fees, err = g.percentileFees(ctx, node)
+		if err != nil {
+			return
+		}
+	}
+
+	if fees.TipCap != nil && g.maxTip != nil && fees.TipCap.Cmp(g.maxTip) > 0 {
+		fees.TipCap = new(big.Int).Set(g.maxTip)
+	}
+	if g.maxPrice != nil && fees.Cap().Cmp(g.maxPrice) > 0 {
+		log.Warn("Gas price is capped by the ceiling", "strategy", g.strategy, "price", fees.Cap().String(), "max", g.maxPrice.String())
+		if fees.GasPrice != nil {
+			fees.GasPrice = new(big.Int).Set(g.maxPrice)
+		} else {
+			fees.FeeCap = new(big.Int).Set(g.maxPrice)
+		}
+	}
+	if fees.TipCap != nil && fees.FeeCap != nil && fees.TipCap.Cmp(fees.FeeCap) > 0 {
+		fees.TipCap = new(big.Int).Set(fees.FeeCap)
+	}
+	return
+}
+
+type feeHistory struct {
+	BaseFee []*hexutil.Big   `json:"baseFeePerGas"`
+	Reward  [][]*hexutil.Big `json:"reward"`
+}
+
+// Tip at the percentile of recent blocks, fee cap is twice of the next base fee plus the tip
+func (g *GasStrategy) percentileFees(ctx context.Context, node *eth.Client) (fees Fees, err error) {
+	var history feeHistory
+	err = node.Rpc.CallContext(ctx, &history, "eth_feeHistory", hexutil.Uint64(g.blocks), "latest", []float64{float64(g.percentile)})
+	if err != nil {
+		return fees, fmt.Errorf("Get fee history error %v", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return fees, fmt.Errorf("Empty fee history")
+	}
+	tips := []*big.Int{}
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			tips = append(tips, reward[0].ToInt())
+		}
+	}
+	fees.TipCap = big.NewInt(0)
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		fees.TipCap = mul(tips[len(tips)/2], g.multiplier)
+	}
+	baseFee := history.BaseFee[len(history.BaseFee)-1].ToInt()
+	fees.FeeCap = new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), fees.TipCap)
+	return
+}
+
+// Min fee bump accepted by nodes to replace a pending tx
+const MIN_BUMP_PERCENT = 10
+
+// Check if the pending tx should be replaced
+func (g *GasStrategy) BumpDue(p *Pending) bool {
+	return g.maxBumps > 0 && len(p.Txs) <= g.maxBumps && time.Since(p.Sent) >= g.bumpTimeout
+}
+
+// Extra time to wait for the receipt after the last replacement
+const CONFIRM_MARGIN = 30 * time.Second
+
+// ConfirmTimeout is long enough for a pending tx to be replaced up to the max bumps
+func (g *GasStrategy) ConfirmTimeout() time.Duration {
+	bumps := g.maxBumps
+	if bumps < 0 {
+		bumps = 0
+	}
+	return g.bumpTimeout*time.Duration(bumps+1) + CONFIRM_MARGIN
+}
+
+// Bump the fees by the bump percent, or to the current strategy fees if higher.
+// Returns false if the bumped fees exceed the ceiling.
+func (g *GasStrategy) Bump(ctx context.Context, node *eth.Client, old Fees) (fees Fees, ok bool) {
+	current, err := g.Fees(ctx, node)
+	if err != nil {
+		current = Fees{}
+	}
+	bump := func(value, now *big.Int) *big.Int {
+		if value == nil {
+			return nil
+		}
+		res := new(big.Int).Div(new(big.Int).Mul(value, big.NewInt(100+g.bumpPercent)), big.NewInt(100))
+		if now != nil && now.Cmp(res) > 0 {
+			res = now
+		}
+		return res
+	}
+	fees = Fees{
+		GasPrice: bump(old.GasPrice, current.GasPrice),
+		TipCap:   bump(old.TipCap, current.TipCap),
+		FeeCap:   bump(old.FeeCap, current.FeeCap),
+	}
+	if g.maxTip != nil && fees.TipCap != nil && fees.TipCap.Cmp(g.maxTip) > 0 {
+		return fees, false
+	}
+	if g.maxPrice != nil && fees.Cap().Cmp(g.maxPrice) > 0 {
+		return fees, false
+	}
+	return fees, true
+}
+
+// Gas limit with safety margin
+func (g *GasStrategy) Limit(estimated uint64) (limit uint64, err error) {
+	limit = uint64(float64(estimated) * (1 + g.limitMargin))
+	if g.maxLimit > 0 && limit > g.maxLimit {
+		if estimated > g.maxLimit {
+			return 0, fmt.Errorf("Estimated gas limit(%v) higher than max %v", estimated, g.maxLimit)
+		}
+		limit = g.maxLimit
+	}
+	return
+}
+
+func mul(value *big.Int, multiplier float64) *big.Int {
+	if multiplier == 1 {
+		return new(big.Int).Set(value)
+	}
+	res, _ := new(big.Float).Mul(new(big.Float).SetInt(value), big.NewFloat(multiplier)).Int(nil)
+	return res
+}
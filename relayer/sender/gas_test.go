@@ -0,0 +1,230 @@
+package sender
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/top/top-relayer/config"
+)
+
+// Node serving the gas price apis
+type fakeGasNode struct {
+	price   int64
+	tip     int64
+	baseFee int64
+	rewards []int64
+}
+
+func (n *fakeGasNode) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n.price))
+}
+
+func (n *fakeGasNode) MaxPriorityFeePerGas() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n.tip))
+}
+
+func (n *fakeGasNode) FeeHistory(blocks hexutil.Uint64, newest string, percentiles []float64) *feeHistory {
+	history := &feeHistory{BaseFee: []*hexutil.Big{(*hexutil.Big)(big.NewInt(n.baseFee))}}
+	for _, reward := range n.rewards {
+		history.Reward = append(history.Reward, []*hexutil.Big{(*hexutil.Big)(big.NewInt(reward))})
+	}
+	return history
+}
+
+func dialGasNode(t *testing.T, node *fakeGasNode) *eth.Client {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+	return &eth.Client{Rpc: client, Client: ethclient.NewClient(client)}
+}
+
+func gasStrategy(t *testing.T, c *config.GasConfig) *GasStrategy {
+	t.Helper()
+	g, err := NewGasStrategy(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func bigs(values ...int64) (list []*big.Int) {
+	for _, v := range values {
+		if v < 0 {
+			list = append(list, nil)
+		} else {
+			list = append(list, big.NewInt(v))
+		}
+	}
+	return
+}
+
+func equalBig(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Cmp(b) == 0
+}
+
+func TestNewGasStrategy(t *testing.T) {
+	cases := []struct {
+		name string
+		conf *config.GasConfig
+		err  bool
+	}{
+		{"default", nil, false},
+		{"fixed without price", &config.GasConfig{Strategy: GAS_FIXED}, true},
+		{"fixed", &config.GasConfig{Strategy: GAS_FIXED, Price: "100"}, false},
+		{"unknown strategy", &config.GasConfig{Strategy: "auto"}, true},
+		{"invalid max fee", &config.GasConfig{Strategy: GAS_EIP1559, MaxFee: "-1"}, true},
+		{"invalid percentile", &config.GasConfig{Strategy: GAS_PERCENTILE, Percentile: 101}, true},
+		{"bump percent too low", &config.GasConfig{BumpPercent: MIN_BUMP_PERCENT - 1}, true},
+	}
+	for _, c := range cases {
+		_, err := NewGasStrategy(c.conf)
+		if (err != nil) != c.err {
+			t.Fatalf("%s: expect error %v, got %v", c.name, c.err, err)
+		}
+	}
+	g := gasStrategy(t, nil)
+	if g.strategy != GAS_SUGGESTED {
+		t.Fatalf("expect legacy suggested gas strategy by default, got %s", g.strategy)
+	}
+}
+
+func TestFees(t *testing.T) {
+	node := &fakeGasNode{price: 100, tip: 10, baseFee: 50, rewards: []int64{3, 1, 2}}
+	cases := []struct {
+		name string
+		conf *config.GasConfig
+		// gas price, tip cap, fee cap, -1 for nil
+		expect []*big.Int
+	}{
+		{"fixed", &config.GasConfig{Strategy: GAS_FIXED, Price: "70"}, bigs(70, -1, -1)},
+		{"fixed capped", &config.GasConfig{Strategy: GAS_FIXED, Price: "70", MaxPrice: "60"}, bigs(60, -1, -1)},
+		{"suggested", nil, bigs(100, -1, -1)},
+		{"suggested with multiplier", &config.GasConfig{Multiplier: 1.5}, bigs(150, -1, -1)},
+		{"suggested capped", &config.GasConfig{Multiplier: 2, MaxPrice: "120"}, bigs(120, -1, -1)},
+		{"eip1559", &config.GasConfig{Strategy: GAS_EIP1559}, bigs(-1, 10, 300)},
+		{"eip1559 with max fee", &config.GasConfig{Strategy: GAS_EIP1559, MaxFee: "200"}, bigs(-1, 10, 200)},
+		{"eip1559 tip capped", &config.GasConfig{Strategy: GAS_EIP1559, MaxTip: "5"}, bigs(-1, 5, 300)},
+		{"eip1559 fee cap below tip", &config.GasConfig{Strategy: GAS_EIP1559, MaxPrice: "8"}, bigs(-1, 8, 8)},
+		{"percentile", &config.GasConfig{Strategy: GAS_PERCENTILE}, bigs(-1, 2, 102)},
+		{"percentile capped", &config.GasConfig{Strategy: GAS_PERCENTILE, MaxPrice: "90"}, bigs(-1, 2, 90)},
+	}
+	client := dialGasNode(t, node)
+	for _, c := range cases {
+		fees, err := gasStrategy(t, c.conf).Fees(context.Background(), client)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got := []*big.Int{fees.GasPrice, fees.TipCap, fees.FeeCap}
+		for i := range got {
+			if !equalBig(got[i], c.expect[i]) {
+				t.Fatalf("%s: expect fees %v, got %v", c.name, c.expect, got)
+			}
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	node := &fakeGasNode{price: 100, tip: 10}
+	cases := []struct {
+		name   string
+		conf   *config.GasConfig
+		old    Fees
+		expect []*big.Int
+		ok     bool
+	}{
+		{"legacy by percent", nil, Fees{GasPrice: big.NewInt(100)}, bigs(120, -1, -1), true},
+		{"legacy to current price", nil, Fees{GasPrice: big.NewInt(50)}, bigs(100, -1, -1), true},
+		{"legacy custom percent", &config.GasConfig{BumpPercent: 50}, Fees{GasPrice: big.NewInt(100)}, bigs(150, -1, -1), true},
+		{"legacy rounds down", nil, Fees{GasPrice: big.NewInt(99)}, bigs(118, -1, -1), true},
+		{"legacy over max price", &config.GasConfig{MaxPrice: "110"}, Fees{GasPrice: big.NewInt(100)}, bigs(120, -1, -1), false},
+		{"legacy at max price", &config.GasConfig{MaxPrice: "120"}, Fees{GasPrice: big.NewInt(100)}, bigs(120, -1, -1), true},
+		{"dynamic", &config.GasConfig{Strategy: GAS_EIP1559, MaxFee: "200"}, Fees{TipCap: big.NewInt(10), FeeCap: big.NewInt(200)}, bigs(-1, 12, 240), true},
+		{"dynamic over max tip", &config.GasConfig{Strategy: GAS_EIP1559, MaxTip: "11"}, Fees{TipCap: big.NewInt(10), FeeCap: big.NewInt(200)}, bigs(-1, 12, 300), false},
+		{"dynamic over max price", &config.GasConfig{Strategy: GAS_EIP1559, MaxPrice: "220"}, Fees{TipCap: big.NewInt(10), FeeCap: big.NewInt(200)}, bigs(-1, 12, 240), false},
+	}
+	client := dialGasNode(t, node)
+	for _, c := range cases {
+		fees, ok := gasStrategy(t, c.conf).Bump(context.Background(), client, c.old)
+		if ok != c.ok {
+			t.Fatalf("%s: expect ok %v, got %v", c.name, c.ok, ok)
+		}
+		got := []*big.Int{fees.GasPrice, fees.TipCap, fees.FeeCap}
+		for i := range got {
+			if !equalBig(got[i], c.expect[i]) {
+				t.Fatalf("%s: expect fees %v, got %v", c.name, c.expect, got)
+			}
+		}
+	}
+}
+
+func TestBumpDue(t *testing.T) {
+	g := gasStrategy(t, &config.GasConfig{BumpTimeout: 10, MaxBumps: 2})
+	old := time.Now().Add(-time.Minute)
+	cases := []struct {
+		name string
+		sent time.Time
+		txs  int
+		due  bool
+	}{
+		{"fresh", time.Now(), 1, false},
+		{"timeout", old, 1, true},
+		{"last bump", old, 2, true},
+		{"max bumps reached", old, 3, false},
+	}
+	for _, c := range cases {
+		p := &Pending{Sent: c.sent}
+		for i := 0; i < c.txs; i++ {
+			p.Txs = append(p.Txs, nil)
+		}
+		if due := g.BumpDue(p); due != c.due {
+			t.Fatalf("%s: expect due %v, got %v", c.name, c.due, due)
+		}
+	}
+	if gasStrategy(t, &config.GasConfig{MaxBumps: -1}).BumpDue(&Pending{Sent: old}) {
+		t.Fatal("expect bump disabled by negative max bumps")
+	}
+	if timeout := g.ConfirmTimeout(); timeout != 30*time.Second+CONFIRM_MARGIN {
+		t.Fatalf("unexpected confirm timeout %s", timeout)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	cases := []struct {
+		name      string
+		conf      *config.GasConfig
+		estimated uint64
+		limit     uint64
+		err       bool
+	}{
+		{"default margin", nil, 100000, 130000, false},
+		{"custom margin", &config.GasConfig{LimitMargin: 0.5}, 100000, 150000, false},
+		{"capped by max", &config.GasConfig{MaxLimit: 120000}, 100000, 120000, false},
+		{"estimated over max", &config.GasConfig{MaxLimit: 90000}, 100000, 0, true},
+	}
+	for _, c := range cases {
+		limit, err := gasStrategy(t, c.conf).Limit(c.estimated)
+		if (err != nil) != c.err {
+			t.Fatalf("%s: expect error %v, got %v", c.name, c.err, err)
+		}
+		if limit != c.limit {
+			t.Fatalf("%s: expect limit %d, got %d", c.name, c.limit, limit)
+		}
+	}
+}
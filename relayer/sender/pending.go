@@ -0,0 +1,188 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/polynetwork/bridge-common/log"
+)
+
+// Pending tx of a nonce, replacements are sent with the same nonce and bumped fees
+type Pending struct {
+	Nonce  uint64
+	To     common.Address
+	Value  *big.Int
+	Data   []byte
+	Limit  uint64
+	Fees   Fees
+	Txs    []*types.Transaction // the original tx followed by replacements
+	Sent   time.Time            // time of the last tx sent
+	Cancel bool                 // replaced with a self transfer
+}
+
+// Latest tx sent with the nonce
+func (p *Pending) Tx() *types.Transaction {
+	return p.Txs[len(p.Txs)-1]
+}
+
+func (s *Sender) track(tx *types.Transaction, fees Fees) {
+	s.pending[tx.Nonce()] = &Pending{
+		Nonce: tx.Nonce(), To: *tx.To(), Value: tx.Value(), Data: tx.Data(), Limit: tx.Gas(), Fees: fees,
+		Txs: []*types.Transaction{tx}, Sent: time.Now(),
+	}
+}
+
+// Drop the txs with nonce below the account latest nonce, the nonce is queried without holding the lock
+func (s *Sender) prune(ctx context.Context) {
+	nonce, err := s.sdk.Node().NonceAt(ctx, s.account.Address, nil)
+	if err != nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	for n := range s.pending {
+		if n < nonce {
+			delete(s.pending, n)
+		}
+	}
+}
+
+func (s *Sender) lookup(hash common.Hash) *Pending {
+	for _, p := range s.pending {
+		for _, tx := range p.Txs {
+			if tx.Hash() == hash {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// Find the pending tx with the same call
+func (s *Sender) find(to common.Address, data []byte) *Pending {
+	for _, p := range s.pending {
+		if !p.Cancel && p.To == to && bytes.Equal(p.Data, data) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Hashes of the txs sharing the nonce with the tx
+func (s *Sender) hashes(hash common.Hash) (list []common.Hash) {
+	s.Lock()
+	defer s.Unlock()
+	p := s.lookup(hash)
+	if p == nil {
+		return []common.Hash{hash}
+	}
+	for _, tx := range p.Txs {
+		list = append(list, tx.Hash())
+	}
+	return
+}
+
+// Check if the mined tx is the cancel tx replacing the tx
+func (s *Sender) cancelled(hash, mined common.Hash) bool {
+	s.Lock()
+	defer s.Unlock()
+	p := s.lookup(hash)
+	if p == nil || !p.Cancel {
+		return false
+	}
+	for _, tx := range p.Txs {
+		if tx.Hash() == mined {
+			return *tx.To() != *p.Txs[0].To()
+		}
+	}
+	return false
+}
+
+func (s *Sender) untrack(hash common.Hash) {
+	s.Lock()
+	defer s.Unlock()
+	if p := s.lookup(hash); p != nil {
+		delete(s.pending, p.Nonce)
+	}
+}
+
+func (s *Sender) speedUp(ctx context.Context, hash common.Hash) {
+	s.Lock()
+	defer s.Unlock()
+	if p := s.lookup(hash); p != nil {
+		s.bump(ctx, p)
+	}
+}
+
+// Replace the pending tx with bumped fees if it has been pending longer than the bump timeout, returns the latest tx
+func (s *Sender) bump(ctx context.Context, p *Pending) *types.Transaction {
+	if !s.gas.BumpDue(p) {
+		return p.Tx()
+	}
+	fees, ok := s.gas.Bump(ctx, s.sdk.Node(), p.Fees)
+	if !ok {
+		log.Warn("Pending tx can not be sped up within gas ceiling", "hash", p.Tx().Hash(), "nonce", p.Nonce, "gas_cap", p.Fees.Cap())
+		p.Sent = time.Now()
+		return p.Tx()
+	}
+	tx, err := s.send(ctx, p.Nonce, p.To, p.Value, p.Data, p.Limit, fees)
+	if err != nil {
+		log.Error("Failed to replace pending tx", "hash", p.Tx().Hash(), "nonce", p.Nonce, "err", err)
+		return p.Tx()
+	}
+	log.Info("Replaced pending tx with bumped fees", "nonce", p.Nonce, "replaced", p.Tx().Hash(), "hash", tx.Hash(), "cancel", p.Cancel)
+	p.Fees = fees
+	p.Txs = append(p.Txs, tx)
+	p.Sent = time.Now()
+	return tx
+}
+
+// Cancel the pending tx with a self transfer on the same nonce
+func (s *Sender) Cancel(ctx context.Context, hash common.Hash) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	p := s.lookup(hash)
+	if p == nil || p.Cancel {
+		return
+	}
+	fees, ok := s.gas.Bump(ctx, s.sdk.Node(), p.Fees)
+	if !ok {
+		fees = p.Fees
+	}
+	tx, err := s.send(ctx, p.Nonce, s.account.Address, nil, nil, 21000, fees)
+	if err != nil {
+		log.Error("Failed to cancel pending tx", "hash", hash, "nonce", p.Nonce, "err", err)
+		return
+	}
+	log.Info("Cancelled obsolete pending tx", "nonce", p.Nonce, "replaced", hash, "hash", tx.Hash())
+	p.To, p.Value, p.Data, p.Limit, p.Fees, p.Cancel = s.account.Address, nil, nil, 21000, fees, true
+	p.Txs = append(p.Txs, tx)
+	p.Sent = time.Now()
+	return
+}
+
+// Check if the tx is sent by the account
+func (s *Sender) Owns(hash common.Hash) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.lookup(hash) != nil
+}
+
+// Check if the same call is pending
+func (s *Sender) Has(to common.Address, data []byte) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.find(to, data) != nil
+}
+
+// Pending tx count of the account
+func (s *Sender) PendingCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.pending)
+}
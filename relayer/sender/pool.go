@@ -0,0 +1,308 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/polynetwork/bridge-common/wallet"
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/metrics"
+	"github.com/top/top-relayer/msg"
+)
+
+// Account selections of the pool
+const (
+	SELECT_ROUND_ROBIN = "round-robin"
+	SELECT_LEAST_BUSY  = "least-busy"
+)
+
+// Interval to refresh the cached account balances
+const BALANCE_TTL = 30 * time.Second
+
+// Pool sends txs with the wallet accounts, each account keeps its own local nonce.
+// Unless parallel is enabled, the pool sticks to one account while it has pending txs,
+// so that consecutive txs are mined in order.
+type Pool struct {
+	sync.Mutex
+	senders    []*Sender
+	selection  string
+	parallel   bool
+	chain      uint64
+	minBalance *big.Int
+	warn       *big.Int
+	critical   *big.Int
+	next       int
+	current    *Sender
+	balances   map[common.Address]*big.Int
+	checked    map[common.Address]time.Time
+	gasConfig  *config.GasConfig
+}
+
+func NewPool(sdk *eth.SDK, w *wallet.Wallet, contract *abi.ABI, conf *config.SubmitterConfig) (p *Pool, err error) {
+	list := w.Accounts()
+	if len(list) == 0 {
+		return nil, fmt.Errorf("No valid account provided")
+	}
+	chainId, err := sdk.Node().ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get chain id %v", err)
+	}
+	gas, err := NewGasStrategy(conf.Gas)
+	if err != nil {
+		return
+	}
+	p = &Pool{
+		chain: conf.ChainId, selection: SELECT_ROUND_ROBIN, parallel: conf.Parallel,
+		balances: map[common.Address]*big.Int{}, checked: map[common.Address]time.Time{}, gasConfig: conf.Gas,
+	}
+	switch conf.Select {
+	case "", SELECT_ROUND_ROBIN:
+	case SELECT_LEAST_BUSY:
+		p.selection = SELECT_LEAST_BUSY
+	default:
+		return nil, fmt.Errorf("Unknown account selection %s", conf.Select)
+	}
+	for _, v := range []struct {
+		name  string
+		value string
+		dst   **big.Int
+	}{
+		{"MinBalance", conf.MinBalance, &p.minBalance},
+		{"BalanceWarn", conf.BalanceWarn, &p.warn},
+		{"BalanceCritical", conf.BalanceCritical, &p.critical},
+	} {
+		if v.value == "" {
+			continue
+		}
+		value, ok := new(big.Int).SetString(v.value, 10)
+		if !ok {
+			return nil, fmt.Errorf("Invalid submitter %s %s", v.name, v.value)
+		}
+		*v.dst = value
+	}
+	// Accounts below critical balance are skipped
+	if p.critical != nil && (p.minBalance == nil || p.minBalance.Cmp(p.critical) < 0) {
+		p.minBalance = p.critical
+	}
+	for _, account := range list {
+		p.senders = append(p.senders, New(sdk, w, account, chainId, gas, contract))
+	}
+	return
+}
+
+// Check if the account balance is above the threshold, balances are cached for a while
+func (p *Pool) funded(s *Sender) bool {
+	if p.minBalance == nil {
+		return true
+	}
+	addr := s.Address()
+	if time.Since(p.checked[addr]) > BALANCE_TTL {
+		p.refresh(s)
+	}
+	balance := p.balances[addr]
+	if balance != nil && balance.Cmp(p.minBalance) < 0 {
+		log.Warn("Skipping account with balance below threshold", "chain", p.chain, "account", addr, "balance", balance, "min", p.minBalance)
+		return false
+	}
+	return true
+}
+
+func (p *Pool) refresh(s *Sender) {
+	addr := s.Address()
+	balance, err := s.sdk.Node().BalanceAt(context.Background(), addr, nil)
+	if err != nil {
+		log.Error("Failed to get account balance", "chain", p.chain, "account", addr, "err", err)
+		return
+	}
+	p.balances[addr] = balance
+	p.checked[addr] = time.Now()
+	metrics.WalletBalance(p.chain, addr.Hex()).Update(new(big.Int).Div(balance, big.NewInt(1e9)).Int64())
+}
+
+// CheckBalance refreshes the account balances against the warn and critical thresholds,
+// returns ERR_LOW_BALANCE if all the accounts are below critical.
+func (p *Pool) CheckBalance() (err error) {
+	p.Lock()
+	defer p.Unlock()
+	usable := 0
+	for _, s := range p.senders {
+		p.refresh(s)
+		addr := s.Address()
+		balance := p.balances[addr]
+		switch {
+		case balance == nil:
+			usable++
+		case p.critical != nil && balance.Cmp(p.critical) < 0:
+			alert.Raise(
+				alert.CRITICAL, fmt.Sprintf("balance/%d/%s", p.chain, addr.Hex()), "Account balance below critical threshold",
+				"Submission paused for account %s on chain %d, balance %s, critical %s", addr.Hex(), p.chain, balance, p.critical,
+			)
+		case p.warn != nil && balance.Cmp(p.warn) < 0:
+			alert.Raise(
+				alert.WARN, fmt.Sprintf("balance/%d/%s", p.chain, addr.Hex()), "Account balance below warn threshold",
+				"Account %s on chain %d, balance %s, warn %s", addr.Hex(), p.chain, balance, p.warn,
+			)
+			usable++
+		default:
+			usable++
+		}
+	}
+	if usable == 0 {
+		return msg.ERR_LOW_BALANCE
+	}
+	return
+}
+
+// Watch the account balances until the context is done
+func (p *Pool) Watch(ctx context.Context, interval time.Duration) {
+	if p.warn == nil && p.critical == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.CheckBalance()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pick an account to send the next tx
+func (p *Pool) pick() (s *Sender, err error) {
+	if !p.parallel && p.current != nil && p.current.PendingCount() > 0 {
+		return p.current, nil
+	}
+	switch p.selection {
+	case SELECT_LEAST_BUSY:
+		busy := -1
+		for _, sender := range p.senders {
+			if count := sender.PendingCount(); (busy < 0 || count < busy) && p.funded(sender) {
+				s, busy = sender, count
+			}
+		}
+	default:
+		for i := range p.senders {
+			sender := p.senders[(p.next+i)%len(p.senders)]
+			if p.funded(sender) {
+				s = sender
+				p.next = (p.next + i + 1) % len(p.senders)
+				break
+			}
+		}
+	}
+	if s == nil {
+		return nil, msg.ERR_LOW_BALANCE
+	}
+	p.current = s
+	return
+}
+
+// owner of the tx, the first account if the tx is not tracked
+func (p *Pool) owner(hash common.Hash) *Sender {
+	for _, s := range p.senders {
+		if s.Owns(hash) {
+			return s
+		}
+	}
+	return p.senders[0]
+}
+
+// Send the tx with a selected account, a pending tx of the same call is sped up instead
+func (p *Pool) Send(ctx context.Context, to common.Address, data []byte, limit uint64) (tx *types.Transaction, err error) {
+	p.Lock()
+	defer p.Unlock()
+	for _, s := range p.senders {
+		if s.Has(to, data) {
+			return s.Send(ctx, to, data, limit)
+		}
+	}
+	s, err := p.pick()
+	if err != nil {
+		return
+	}
+	return s.Send(ctx, to, data, limit)
+}
+
+func (p *Pool) Confirm(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return p.owner(hash).Confirm(ctx, hash)
+}
+
+// Accounts of the pool
+func (p *Pool) Accounts() (list []common.Address) {
+	for _, s := range p.senders {
+		list = append(list, s.Address())
+	}
+	return
+}
+
+// Get the sender of the account, nil if not in the pool
+func (p *Pool) Get(address common.Address) *Sender {
+	for _, s := range p.senders {
+		if s.Address() == address {
+			return s
+		}
+	}
+	return nil
+}
+
+func (p *Pool) Cancel(ctx context.Context, hash common.Hash) error {
+	return p.owner(hash).Cancel(ctx, hash)
+}
+
+// SetGas replaces the gas strategy of all accounts, txs already pending keep their fees
+func (p *Pool) SetGas(gas *GasStrategy) {
+	for _, s := range p.senders {
+		s.Lock()
+		s.gas = gas
+		s.Unlock()
+	}
+}
+
+// ReloadGas replaces the gas strategy if the gas config changed
+func (p *Pool) ReloadGas(c *config.GasConfig) (err error) {
+	p.Lock()
+	defer p.Unlock()
+	if reflect.DeepEqual(c, p.gasConfig) {
+		return
+	}
+	gas, err := NewGasStrategy(c)
+	if err != nil {
+		return
+	}
+	p.SetGas(gas)
+	p.gasConfig = c
+	log.Info("Reloaded submitter gas strategy", "chain", p.chain)
+	return
+}
+
+// Reset drops the local nonces of all accounts
+func (p *Pool) Reset() {
+	for _, s := range p.senders {
+		s.Reset()
+	}
+}
+
+func (p *Pool) Status() (list []Status, err error) {
+	for _, s := range p.senders {
+		status, err := s.Status()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, status)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package sender
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/top/top-relayer/msg"
+)
+
+func testPool(t *testing.T, sdk *eth.SDK, selection string, parallel bool, count int) *Pool {
+	t.Helper()
+	p := &Pool{
+		selection: selection, parallel: parallel,
+		balances: map[common.Address]*big.Int{}, checked: map[common.Address]time.Time{},
+	}
+	for i := 0; i < count; i++ {
+		p.senders = append(p.senders, testSender(t, sdk, nil))
+	}
+	return p
+}
+
+// Accounts used to send the calls in order
+func sendCalls(t *testing.T, p *Pool, count int) (list []common.Address) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		tx, err := p.Send(context.Background(), common.HexToAddress("0x01"), []byte{byte(i)}, 50000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, p.owner(tx.Hash()).Address())
+	}
+	return
+}
+
+func TestPoolSelection(t *testing.T) {
+	sdk := dialNode(t, newFakeNode())
+
+	p := testPool(t, sdk, SELECT_ROUND_ROBIN, true, 2)
+	used := sendCalls(t, p, 3)
+	if used[0] != p.senders[0].Address() || used[1] != p.senders[1].Address() || used[2] != p.senders[0].Address() {
+		t.Fatalf("expect accounts picked in turn, got %v", used)
+	}
+
+	p = testPool(t, sdk, SELECT_ROUND_ROBIN, false, 2)
+	used = sendCalls(t, p, 2)
+	if used[0] != used[1] {
+		t.Fatalf("expect the account with pending txs kept without parallel, got %v", used)
+	}
+
+	p = testPool(t, sdk, SELECT_LEAST_BUSY, true, 2)
+	p.senders[0].Send(context.Background(), common.HexToAddress("0x02"), []byte{1}, 50000)
+	if used = sendCalls(t, p, 1); used[0] != p.senders[1].Address() {
+		t.Fatalf("expect the least busy account picked, got %v", used)
+	}
+}
+
+func TestPoolMinBalance(t *testing.T) {
+	node := newFakeNode()
+	node.balance = 100
+	p := testPool(t, dialNode(t, node), SELECT_ROUND_ROBIN, true, 2)
+	p.minBalance = big.NewInt(200)
+	if _, err := p.Send(context.Background(), common.HexToAddress("0x01"), []byte{1}, 50000); err != msg.ERR_LOW_BALANCE {
+		t.Fatalf("expect no account funded, got %v", err)
+	}
+
+	p.minBalance = big.NewInt(100)
+	p.checked = map[common.Address]time.Time{}
+	if _, err := p.Send(context.Background(), common.HexToAddress("0x01"), []byte{1}, 50000); err != nil {
+		t.Fatalf("expect funded account picked, got %v", err)
+	}
+}
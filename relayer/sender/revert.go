@@ -0,0 +1,197 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/top/top-relayer/msg"
+)
+
+// Revert kinds
+const (
+	REVERT_ERROR   = "error"  // Error(string)
+	REVERT_PANIC   = "panic"  // Panic(uint256)
+	REVERT_CUSTOM  = "custom" // custom error defined in contract abi
+	REVERT_UNKNOWN = "unknown"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// Solidity panic codes
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero initialized function",
+}
+
+// Known revert reasons of the light client contracts, matched in order against the lower cased reason
+var revertReasons = []struct {
+	reason string
+	err    error
+}{
+	{"parent header not exist", msg.ERR_HEADER_INCONSISTENT},
+	{"parent block failed", msg.ERR_HEADER_INCONSISTENT},
+	{"missing required field", msg.ERR_HEADER_INCONSISTENT},
+	{"span not correct", msg.ERR_HEADER_INCONSISTENT},
+	{"verifyspan err", msg.ERR_HEADER_INCONSISTENT},
+	{"already exist", msg.ERR_HEADER_EXISTS},
+	{"not initialized", msg.ERR_CONTRACT_UNINITIALIZED},
+}
+
+// Short revert reasons too generic to match as substrings, compared with the whole lower cased reason
+var exactReasons = map[string]error{
+	"paused":           msg.ERR_CONTRACT_PAUSED,
+	"pausable: paused": msg.ERR_CONTRACT_PAUSED,
+}
+
+// RevertError is returned when a tx is reverted by contract execution
+type RevertError struct {
+	Kind   string
+	Reason string
+	Code   uint64 // panic code
+	Data   []byte
+}
+
+func (e *RevertError) Error() string {
+	if e.Kind == REVERT_PANIC {
+		return fmt.Sprintf("Execution reverted with panic 0x%x: %s", e.Code, e.Reason)
+	}
+	return fmt.Sprintf("Execution reverted: %s", e.Reason)
+}
+
+// DecodeRevert decodes the revert data, custom errors are looked up in the contract abi if provided
+func DecodeRevert(data []byte, contract *abi.ABI) *RevertError {
+	e := &RevertError{Kind: REVERT_UNKNOWN, Data: data}
+	if len(data) < 4 {
+		e.Reason = "no revert data"
+		return e
+	}
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		reason, err := abi.UnpackRevert(data)
+		if err == nil {
+			e.Kind, e.Reason = REVERT_ERROR, reason
+			return e
+		}
+	case bytes.Equal(data[:4], panicSelector) && len(data) >= 36:
+		e.Kind = REVERT_PANIC
+		e.Code = new(big.Int).SetBytes(data[4:36]).Uint64()
+		e.Reason = panicReasons[e.Code]
+		if e.Reason == "" {
+			e.Reason = "unknown panic"
+		}
+		return e
+	case contract != nil:
+		for _, custom := range contract.Errors {
+			if !bytes.Equal(custom.ID[:4], data[:4]) {
+				continue
+			}
+			args, err := custom.Inputs.Unpack(data[4:])
+			if err != nil {
+				break
+			}
+			e.Kind = REVERT_CUSTOM
+			e.Reason = custom.Name
+			if len(args) > 0 {
+				e.Reason = fmt.Sprintf("%s%v", custom.Name, args)
+			}
+			return e
+		}
+	}
+	e.Reason = hexutil.Encode(data)
+	return e
+}
+
+// RevertFromError extracts the revert from rpc errors of eth_call or eth_estimateGas, returns nil if not reverted
+func RevertFromError(err error, contract *abi.ABI) *RevertError {
+	if err == nil {
+		return nil
+	}
+	var revert *RevertError
+	if errors.As(err, &revert) {
+		return revert
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hex, ok := dataErr.ErrorData().(string); ok {
+			data, e := hexutil.Decode(hex)
+			if e == nil && len(data) > 0 {
+				return DecodeRevert(data, contract)
+			}
+		}
+	}
+	// Some nodes do not return revert data, the reason is appended to the error message instead
+	info := err.Error()
+	if i := strings.Index(info, "execution reverted"); i >= 0 {
+		reason := strings.TrimPrefix(strings.TrimPrefix(info[i:], "execution reverted"), ":")
+		return &RevertError{Kind: REVERT_ERROR, Reason: strings.TrimSpace(reason)}
+	}
+	return nil
+}
+
+// ClassifyError maps the submit error to the typed errors in msg, returns nil for transient failures
+func ClassifyError(err error) error {
+	if err == msg.ERR_TX_CANCELLED {
+		// The cancel tx was mined instead, later submissions depend on the missing one
+		return err
+	}
+	var revert *RevertError
+	if !errors.As(err, &revert) {
+		return nil
+	}
+	if revert.Kind == REVERT_PANIC {
+		return msg.ERR_CONTRACT_PANIC
+	}
+	reason := strings.ToLower(strings.TrimSpace(revert.Reason))
+	if typed, ok := exactReasons[reason]; ok {
+		return typed
+	}
+	for _, r := range revertReasons {
+		if strings.Contains(reason, r.reason) {
+			return r.err
+		}
+	}
+	return msg.ERR_TX_EXEC_FAILURE
+}
+
+// Replay the failed tx with eth_call at the block it was included to get the revert reason
+func (s *Sender) replay(ctx context.Context, hash common.Hash, receipt *types.Receipt) error {
+	tx, _, err := s.sdk.Node().TransactionByHash(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("Tx %s execution reverted at height %v, fetch tx error %v", hash, receipt.BlockNumber, err)
+	}
+	call := ethereum.CallMsg{
+		From: s.account.Address, To: tx.To(), Gas: tx.Gas(),
+		GasFeeCap: tx.GasFeeCap(), GasTipCap: tx.GasTipCap(), Value: tx.Value(), Data: tx.Data(),
+	}
+	_, err = s.sdk.Node().CallContract(ctx, call, receipt.BlockNumber)
+	if revert := RevertFromError(err, s.abi); revert != nil {
+		return revert
+	}
+	if err != nil {
+		return fmt.Errorf("Tx %s execution reverted at height %v, replay error %v", tx.Hash(), receipt.BlockNumber, err)
+	}
+	return fmt.Errorf("Tx %s execution reverted at height %v", tx.Hash(), receipt.BlockNumber)
+}
@@ -0,0 +1,127 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/top/top-relayer/msg"
+)
+
+const testErrorsABI = `[
+	{"type": "error", "name": "Paused", "inputs": []},
+	{"type": "error", "name": "InvalidHeight", "inputs": [{"name": "height", "type": "uint256"}]}
+]`
+
+func packRevert(t *testing.T, selector []byte, kind string, value interface{}) []byte {
+	t.Helper()
+	typ, err := abi.NewType(kind, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := abi.Arguments{{Type: typ}}.Pack(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, selector...), data...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(testErrorsABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidHeight := contract.Errors["InvalidHeight"].ID.Bytes()[:4]
+	cases := []struct {
+		name     string
+		data     []byte
+		contract *abi.ABI
+		kind     string
+		reason   string
+		code     uint64
+	}{
+		{"no data", nil, nil, REVERT_UNKNOWN, "no revert data", 0},
+		{"error string", packRevert(t, errorSelector, "string", "parent header not exist"), nil, REVERT_ERROR, "parent header not exist", 0},
+		{"panic", packRevert(t, panicSelector, "uint256", big.NewInt(0x11)), nil, REVERT_PANIC, "arithmetic overflow or underflow", 0x11},
+		{"unknown panic", packRevert(t, panicSelector, "uint256", big.NewInt(0x99)), nil, REVERT_PANIC, "unknown panic", 0x99},
+		{"custom error", packRevert(t, invalidHeight, "uint256", big.NewInt(7)), &contract, REVERT_CUSTOM, "InvalidHeight[7]", 0},
+		{"custom error without args", contract.Errors["Paused"].ID.Bytes()[:4], &contract, REVERT_CUSTOM, "Paused", 0},
+		{"custom error without abi", packRevert(t, invalidHeight, "uint256", big.NewInt(7)), nil, REVERT_UNKNOWN, "", 0},
+		{"unknown selector", []byte{1, 2, 3, 4}, &contract, REVERT_UNKNOWN, "0x01020304", 0},
+	}
+	for _, c := range cases {
+		revert := DecodeRevert(c.data, c.contract)
+		reason := c.reason
+		if reason == "" {
+			reason = hexutil.Encode(c.data)
+		}
+		if revert.Kind != c.kind || revert.Reason != reason || revert.Code != c.code {
+			t.Fatalf("%s: expect %s %q %d, got %s %q %d", c.name, c.kind, reason, c.code, revert.Kind, revert.Reason, revert.Code)
+		}
+	}
+}
+
+// Rpc error carrying the revert data
+type dataError struct {
+	data interface{}
+}
+
+func (e *dataError) Error() string          { return "execution reverted" }
+func (e *dataError) ErrorData() interface{} { return e.data }
+
+func TestRevertFromError(t *testing.T) {
+	reason := packRevert(t, errorSelector, "string", "already exist")
+	cases := []struct {
+		name   string
+		err    error
+		reason string // empty if not reverted
+	}{
+		{"nil", nil, ""},
+		{"not reverted", errors.New("connection refused"), ""},
+		{"revert data", &dataError{hexutil.Encode(reason)}, "already exist"},
+		{"wrapped revert data", fmt.Errorf("estimate: %w", &dataError{hexutil.Encode(reason)}), "already exist"},
+		{"message", errors.New("execution reverted: Pausable: paused"), "Pausable: paused"},
+		{"revert error", &RevertError{Kind: REVERT_ERROR, Reason: "not initialized"}, "not initialized"},
+	}
+	for _, c := range cases {
+		revert := RevertFromError(c.err, nil)
+		if c.reason == "" {
+			if revert != nil {
+				t.Fatalf("%s: expect not reverted, got %v", c.name, revert)
+			}
+			continue
+		}
+		if revert == nil || revert.Reason != c.reason {
+			t.Fatalf("%s: expect reason %q, got %v", c.name, c.reason, revert)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	reverted := func(reason string) error { return &RevertError{Kind: REVERT_ERROR, Reason: reason} }
+	cases := []struct {
+		name string
+		err  error
+		typ  error
+	}{
+		{"not reverted", errors.New("contract paused by node"), nil},
+		{"panic", &RevertError{Kind: REVERT_PANIC, Code: 1}, msg.ERR_CONTRACT_PANIC},
+		{"parent missing", reverted("Parent header not exist"), msg.ERR_HEADER_INCONSISTENT},
+		{"exists", reverted("header already exist"), msg.ERR_HEADER_EXISTS},
+		{"uninitialized", reverted("contract not initialized"), msg.ERR_CONTRACT_UNINITIALIZED},
+		{"paused", reverted("Paused"), msg.ERR_CONTRACT_PAUSED},
+		{"pausable", reverted(" Pausable: paused "), msg.ERR_CONTRACT_PAUSED},
+		{"paused in longer reason", reverted("unpaused flag mismatch"), msg.ERR_TX_EXEC_FAILURE},
+		{"unknown reason", reverted("gas too low"), msg.ERR_TX_EXEC_FAILURE},
+	}
+	for _, c := range cases {
+		if typ := ClassifyError(c.err); typ != c.typ {
+			t.Fatalf("%s: expect %v, got %v", c.name, c.typ, typ)
+		}
+	}
+}
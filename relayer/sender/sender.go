@@ -0,0 +1,221 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/polynetwork/bridge-common/wallet"
+	"github.com/top/top-relayer/msg"
+)
+
+// Sender signs and sends txs for a single wallet account, tracking the account nonce locally
+// so that consecutive txs can be pipelined without waiting for each other to be mined.
+type Sender struct {
+	sync.Mutex
+	sdk      *eth.SDK
+	wallet   *wallet.Wallet
+	account  accounts.Account
+	provider wallet.Provider
+	abi      *abi.ABI // target contract abi to decode custom errors
+	gas      *GasStrategy
+	chainId  *big.Int
+	nonce    uint64
+	synced   bool
+	pending  map[uint64]*Pending // pending txs by nonce
+}
+
+func New(sdk *eth.SDK, w *wallet.Wallet, account accounts.Account, chainId *big.Int, gas *GasStrategy, contract *abi.ABI) *Sender {
+	s := &Sender{sdk: sdk, wallet: w, account: account, abi: contract, gas: gas, chainId: chainId, pending: map[uint64]*Pending{}}
+	s.provider, _ = w.GetAccount(account)
+	return s
+}
+
+func (s *Sender) Address() common.Address {
+	return s.account.Address
+}
+
+// Account status of the sender
+type Status struct {
+	Address string
+	Balance *big.Int
+	Nonce   uint64 // nonce of the latest block
+	Pending uint64 // txs pending in mempool
+}
+
+func (s *Sender) Status() (status Status, err error) {
+	status.Address = s.account.Address.Hex()
+	status.Balance, err = s.sdk.Node().BalanceAt(context.Background(), s.account.Address, nil)
+	if err != nil {
+		return
+	}
+	status.Nonce, err = s.sdk.Node().NonceAt(context.Background(), s.account.Address, nil)
+	if err != nil {
+		return
+	}
+	pending, err := s.sdk.Node().PendingNonceAt(context.Background(), s.account.Address)
+	if err != nil {
+		return
+	}
+	if pending > status.Nonce {
+		status.Pending = pending - status.Nonce
+	}
+	if count := uint64(s.PendingCount()); count > status.Pending {
+		status.Pending = count
+	}
+	return
+}
+
+// Reset drops the local nonce, the next tx will fetch the pending nonce from the node again
+func (s *Sender) Reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.synced = false
+}
+
+func (s *Sender) acquire(ctx context.Context) (nonce uint64, err error) {
+	if !s.synced {
+		s.nonce, err = s.sdk.Node().PendingNonceAt(ctx, s.account.Address)
+		if err != nil {
+			return
+		}
+		s.synced = true
+	}
+	return s.nonce, nil
+}
+
+// Send composes a legacy or dynamic fee tx by the gas strategy with the next local nonce. Gas limit will be estimated if limit is zero.
+func (s *Sender) Send(ctx context.Context, to common.Address, data []byte, limit uint64) (tx *types.Transaction, err error) {
+	return s.SendValue(ctx, to, nil, data, limit)
+}
+
+// SendValue sends the tx with native token value attached, nothing is sent once the ctx is done
+func (s *Sender) SendValue(ctx context.Context, to common.Address, value *big.Int, data []byte, limit uint64) (tx *types.Transaction, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	// The same tx is still pending, speed it up instead of sending with a new nonce
+	if p := s.find(to, data); p != nil {
+		return s.bump(ctx, p), nil
+	}
+
+	fees, err := s.gas.Fees(ctx, s.sdk.Node())
+	if err != nil {
+		return
+	}
+
+	if limit == 0 {
+		msg := ethereum.CallMsg{
+			From: s.account.Address, To: &to, Value: value, Data: data,
+			GasPrice: fees.GasPrice, GasFeeCap: fees.FeeCap, GasTipCap: fees.TipCap,
+		}
+		limit, err = s.sdk.Node().EstimateGas(ctx, msg)
+		if revert := RevertFromError(err, s.abi); revert != nil {
+			return nil, revert
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Estimate gas limit error %v", err)
+		}
+		limit, err = s.gas.Limit(limit)
+		if err != nil {
+			return
+		}
+	}
+	max := wallet.GetChainGasLimit(s.chainId.Uint64(), limit)
+	if max < limit {
+		return nil, fmt.Errorf("Send tx estimated gas limit(%v) higher than max %v", limit, max)
+	}
+
+	nonce, err := s.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Get account nonce error %v", err)
+	}
+	tx, err = s.send(ctx, nonce, to, value, data, limit, fees)
+	if err != nil {
+		s.synced = false
+		return nil, err
+	}
+	s.nonce++
+	s.track(tx, fees)
+	return
+}
+
+// Sign and send the tx with the nonce and fees
+func (s *Sender) send(ctx context.Context, nonce uint64, to common.Address, value *big.Int, data []byte, limit uint64, fees Fees) (tx *types.Transaction, err error) {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if fees.GasPrice != nil {
+		tx = types.NewTransaction(nonce, to, value, limit, fees.GasPrice, data)
+	} else {
+		tx = types.NewTx(&types.DynamicFeeTx{
+			Nonce:     nonce,
+			GasTipCap: fees.TipCap,
+			GasFeeCap: fees.FeeCap,
+			Gas:       limit,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+	tx, err = s.provider.SignTx(s.account, tx, s.chainId)
+	if err != nil {
+		return nil, fmt.Errorf("Sign tx error %v", err)
+	}
+	err = s.sdk.Node().SendTransaction(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Sent tx", "hash", tx.Hash().String(), "account", s.account.Address, "nonce", nonce, "gas_limit", limit, "gas_cap", fees.Cap().String())
+	return
+}
+
+// Confirm waits for the tx receipt, a reverted tx is replayed to return the decoded RevertError.
+// Pending txs are replaced with bumped fees once the bump timeout is reached, ERR_TX_TIMEOUT is returned
+// if the tx is still pending after the max replacements, and ERR_TX_CANCELLED if the cancel tx of the nonce was mined.
+func (s *Sender) Confirm(ctx context.Context, hash common.Hash) (receipt *types.Receipt, err error) {
+	s.Lock()
+	deadline := time.Now().Add(s.gas.ConfirmTimeout())
+	s.Unlock()
+	for {
+		for _, h := range s.hashes(hash) {
+			receipt, err = s.sdk.Node().TransactionReceipt(ctx, h)
+			if err == nil && receipt != nil {
+				cancelled := s.cancelled(hash, h)
+				s.untrack(hash)
+				s.prune(ctx)
+				if cancelled {
+					log.Warn("Tx cancelled by the self transfer of the nonce", "hash", hash, "cancel", h, "account", s.account.Address)
+					return receipt, msg.ERR_TX_CANCELLED
+				}
+				if receipt.Status != types.ReceiptStatusSuccessful {
+					err = s.replay(ctx, h, receipt)
+				}
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			log.Error("Tx confirmation timeout", "hash", hash, "account", s.account.Address)
+			return nil, msg.ERR_TX_TIMEOUT
+		}
+		s.speedUp(ctx, hash)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package sender
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+)
+
+// Node accepting the txs into a mempool, txs are mined by the test
+type fakeNode struct {
+	fakeGasNode
+	mu      sync.Mutex
+	sent    []*types.Transaction
+	mined   map[common.Hash]bool
+	nonces  map[common.Address]uint64 // nonce of the latest block
+	balance int64
+}
+
+func (n *fakeNode) BlockNumber() hexutil.Uint64 {
+	return 1
+}
+
+func (n *fakeNode) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return hexutil.Uint64(n.nonces[address])
+}
+
+func (n *fakeNode) GetBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n.balance))
+}
+
+func (n *fakeNode) SendRawTransaction(data hexutil.Bytes) (hash common.Hash, err error) {
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(data); err != nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, tx)
+	return tx.Hash(), nil
+}
+
+func (n *fakeNode) GetTransactionReceipt(hash common.Hash) *types.Receipt {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.mined[hash] {
+		return nil
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: hash, BlockNumber: big.NewInt(1), Logs: []*types.Log{}}
+}
+
+// Mine the tx, the account nonce moves past it
+func (n *fakeNode) mine(from common.Address, tx *types.Transaction) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.mined[tx.Hash()] = true
+	if tx.Nonce() >= n.nonces[from] {
+		n.nonces[from] = tx.Nonce() + 1
+	}
+}
+
+func (n *fakeNode) txs() []*types.Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*types.Transaction{}, n.sent...)
+}
+
+func dialNode(t *testing.T, node *fakeNode) *eth.SDK {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatal(err)
+	}
+	http := httptest.NewServer(server)
+	t.Cleanup(func() {
+		http.Close()
+		server.Stop()
+	})
+	sdk, err := eth.NewSDK(1, []string{http.URL}, time.Minute, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sdk
+}
+
+// Provider signing with a local key
+type keyProvider struct {
+	key *ecdsa.PrivateKey
+}
+
+func (p *keyProvider) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), p.key)
+}
+
+func (p *keyProvider) Init(accounts.Account) error { return nil }
+
+func (p *keyProvider) Accounts() []accounts.Account { return nil }
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{fakeGasNode: fakeGasNode{price: 100}, mined: map[common.Hash]bool{}, nonces: map[common.Address]uint64{}}
+}
+
+func testSender(t *testing.T, sdk *eth.SDK, gas *config.GasConfig) *Sender {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Sender{
+		sdk: sdk, account: accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}, provider: &keyProvider{key},
+		gas: gasStrategy(t, gas), chainId: big.NewInt(1), pending: map[uint64]*Pending{},
+	}
+}
+
+// Mark the pending tx of the nonce as due for a bump
+func (s *Sender) expire(nonce uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.pending[nonce].Sent = time.Now().Add(-time.Hour)
+}
+
+func TestSendReplace(t *testing.T) {
+	node := newFakeNode()
+	s := testSender(t, dialNode(t, node), &config.GasConfig{BumpTimeout: 10, MaxBumps: 2})
+	ctx := context.Background()
+	contract := common.HexToAddress("0x01")
+
+	tx, err := s.Send(ctx, contract, []byte{1}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.Send(ctx, contract, []byte{2}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 0 || next.Nonce() != 1 {
+		t.Fatalf("expect consecutive nonces, got %d %d", tx.Nonce(), next.Nonce())
+	}
+	// The same call is still pending, it is not sent again before the bump timeout
+	if again, _ := s.Send(ctx, contract, []byte{1}, 50000); again.Hash() != tx.Hash() || len(node.txs()) != 2 {
+		t.Fatalf("expect pending tx returned for the same call, sent %d txs", len(node.txs()))
+	}
+
+	s.expire(0)
+	s.speedUp(ctx, tx.Hash())
+	sent := node.txs()
+	if len(sent) != 3 {
+		t.Fatalf("expect replacement sent, got %d txs", len(sent))
+	}
+	replacement := sent[2]
+	if replacement.Nonce() != 0 || replacement.GasPrice().Int64() != 120 {
+		t.Fatalf("expect replacement of nonce 0 with bumped price 120, got nonce %d price %v", replacement.Nonce(), replacement.GasPrice())
+	}
+	if hashes := s.hashes(tx.Hash()); len(hashes) != 2 || hashes[1] != replacement.Hash() {
+		t.Fatalf("expect replacement tracked with the original, got %v", hashes)
+	}
+
+	// Confirmed by the replacement
+	node.mine(s.Address(), replacement)
+	receipt, err := s.Confirm(ctx, tx.Hash())
+	if err != nil || receipt.TxHash != replacement.Hash() {
+		t.Fatalf("expect confirmed by replacement, got %v %v", receipt, err)
+	}
+	if s.PendingCount() != 1 {
+		t.Fatalf("expect confirmed nonce untracked, %d pending", s.PendingCount())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	node := newFakeNode()
+	s := testSender(t, dialNode(t, node), nil)
+	ctx := context.Background()
+	contract := common.HexToAddress("0x01")
+
+	tx, err := s.Send(ctx, contract, []byte{1}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Cancel(ctx, tx.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	sent := node.txs()
+	if len(sent) != 2 {
+		t.Fatalf("expect cancel tx sent, got %d txs", len(sent))
+	}
+	cancel := sent[1]
+	if cancel.Nonce() != tx.Nonce() || *cancel.To() != s.Address() || len(cancel.Data()) != 0 || cancel.GasPrice().Int64() <= tx.GasPrice().Int64() {
+		t.Fatalf("expect self transfer with the same nonce and bumped price, got %+v", cancel)
+	}
+	// Cancelled call is not reused by a new send
+	if s.Has(contract, []byte{1}) {
+		t.Fatal("expect cancelled call not pending")
+	}
+	if err = s.Cancel(ctx, tx.Hash()); err != nil || len(node.txs()) != 2 {
+		t.Fatal("expect cancelled tx not cancelled again")
+	}
+
+	node.mine(s.Address(), cancel)
+	if _, err = s.Confirm(ctx, tx.Hash()); err != msg.ERR_TX_CANCELLED {
+		t.Fatalf("expect tx cancelled, got %v", err)
+	}
+	if ClassifyError(err) != msg.ERR_TX_CANCELLED || msg.SubmitPolicy(err) != msg.POLICY_ROLLBACK {
+		t.Fatal("expect cancelled tx to reset the submission")
+	}
+	if s.PendingCount() != 0 {
+		t.Fatalf("expect cancelled nonce untracked, %d pending", s.PendingCount())
+	}
+}
+
+func TestConfirmOriginalAfterCancel(t *testing.T) {
+	node := newFakeNode()
+	s := testSender(t, dialNode(t, node), nil)
+	ctx := context.Background()
+
+	tx, err := s.Send(ctx, common.HexToAddress("0x01"), []byte{1}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Cancel(ctx, tx.Hash())
+	// The original tx was mined before the cancel tx replaced it
+	node.mine(s.Address(), tx)
+	receipt, err := s.Confirm(ctx, tx.Hash())
+	if err != nil || receipt.TxHash != tx.Hash() {
+		t.Fatalf("expect original tx confirmed, got %v %v", receipt, err)
+	}
+}
+
+func TestPruneOnConfirm(t *testing.T) {
+	node := newFakeNode()
+	s := testSender(t, dialNode(t, node), nil)
+	ctx := context.Background()
+	contract := common.HexToAddress("0x01")
+
+	if _, err := s.Send(ctx, contract, []byte{1}, 50000); err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.Send(ctx, contract, []byte{2}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PendingCount() != 2 {
+		t.Fatalf("expect 2 pending txs, got %d", s.PendingCount())
+	}
+	// The later nonce is mined, the earlier one must have been mined before it
+	node.mine(s.Address(), next)
+	if _, err = s.Confirm(ctx, next.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if s.PendingCount() != 0 {
+		t.Fatalf("expect nonces below the account nonce pruned, %d pending", s.PendingCount())
+	}
+}
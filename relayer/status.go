@@ -0,0 +1,142 @@
+package relayer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/polynetwork/bridge-common/chains/eth"
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/relayer/eth"
+)
+
+// Header sync status of a single direction
+type SyncStatus struct {
+	Chain   uint64
+	Source  string
+	Target  string
+	Tip     uint64  // source chain latest height
+	Synced  uint64  // light client height on the target chain
+	Lag     uint64  // blocks behind the source chain tip
+	CatchUp float64 `json:",omitempty"` // estimated seconds to catch up, zero if unknown or not catching up
+	Flags   uint64  `json:",omitempty"` // pause flags of the light client contract
+	Error   string  `json:",omitempty"`
+
+	listener IChainListener
+	time     time.Time
+}
+
+// Direction label of the status
+func (s *SyncStatus) Direction() string {
+	return fmt.Sprintf("%s -> %s", s.Source, s.Target)
+}
+
+func (s *SyncStatus) update() {
+	var err error
+	s.time = time.Now()
+	s.Tip, err = s.listener.LatestHeight()
+	if err == nil {
+		s.Synced, err = s.listener.LastHeaderSync(0, 0)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+	if reader, ok := s.listener.(IPauseReader); ok {
+		s.Flags, err = reader.PauseFlags()
+		if err != nil {
+			s.Error = err.Error()
+			return
+		}
+	}
+	s.Error = ""
+	s.Lag = 0
+	if s.Tip > s.Synced {
+		s.Lag = s.Tip - s.Synced
+	}
+}
+
+// Suspended returns if the light client contract paused adding blocks
+func (s *SyncStatus) Suspended() bool {
+	return s.Flags&eth.PAUSED_ADD_BLOCK != 0
+}
+
+// Estimate catch up time with the rates between two samples
+func (s *SyncStatus) estimate(last *SyncStatus) {
+	s.CatchUp = 0
+	elapse := s.time.Sub(last.time).Seconds()
+	if s.Error != "" || last.Error != "" || elapse <= 0 || s.Lag == 0 {
+		return
+	}
+	rate := (float64(s.Synced) - float64(last.Synced) - float64(s.Tip) + float64(last.Tip)) / elapse
+	if rate > 0 {
+		s.CatchUp = float64(s.Lag) / rate
+	}
+}
+
+// Create status readers for every configured header sync direction
+func NewSyncStatus(conf *config.Config) (list []*SyncStatus, err error) {
+	ids := []uint64{}
+	for id := range conf.Chains {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		for _, hs := range conf.Chains[id].HeaderSync {
+			if hs == nil || hs.ListenerConfig == nil || hs.Submitter == nil {
+				continue
+			}
+			listener := GetListener(hs.ChainId)
+			if listener == nil {
+				return nil, fmt.Errorf("No listener for chain %d available", hs.ChainId)
+			}
+			peer, err := ethcommon.WithOptions(hs.Submitter.ChainId, hs.Submitter.Nodes, time.Minute, 1)
+			if err != nil {
+				return nil, err
+			}
+			err = listener.Init(hs, peer)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, &SyncStatus{
+				Chain:    id,
+				Source:   base.GetChainName(hs.ChainId),
+				Target:   base.GetChainName(hs.Submitter.ChainId),
+				listener: listener,
+			})
+		}
+	}
+	return
+}
+
+// Sample the status twice with the interval to estimate the catch up time
+func SampleSyncStatus(list []*SyncStatus, interval time.Duration) {
+	last := make([]SyncStatus, len(list))
+	update(list)
+	if interval <= 0 {
+		return
+	}
+	for i, s := range list {
+		last[i] = *s
+	}
+	time.Sleep(interval)
+	update(list)
+	for i, s := range list {
+		s.estimate(&last[i])
+	}
+}
+
+func update(list []*SyncStatus) {
+	wg := &sync.WaitGroup{}
+	for _, s := range list {
+		wg.Add(1)
+		go func(s *SyncStatus) {
+			defer wg.Done()
+			s.update()
+		}(s)
+	}
+	wg.Wait()
+}
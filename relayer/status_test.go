@@ -0,0 +1,115 @@
+package relayer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/top/top-relayer/relayer/eth"
+)
+
+// Listener reporting the source chain tip and light client height
+type statusListener struct {
+	IChainListener
+	tip, synced uint64
+	err         error
+}
+
+func (l *statusListener) LatestHeight() (uint64, error) { return l.tip, nil }
+
+func (l *statusListener) LastHeaderSync(force, last uint64) (uint64, error) { return l.synced, l.err }
+
+func TestSyncStatusUpdate(t *testing.T) {
+	listener := &statusListener{tip: 100, synced: 90}
+	s := &SyncStatus{Source: "eth", Target: "top", listener: listener}
+	s.update()
+	if s.Tip != 100 || s.Synced != 90 || s.Lag != 10 || s.Error != "" || s.Direction() != "eth -> top" {
+		t.Fatalf("expect lag 10, got %+v", s)
+	}
+
+	// Light client ahead of a lagging source node
+	listener.synced = 110
+	if s.update(); s.Lag != 0 {
+		t.Fatalf("expect no lag, got %d", s.Lag)
+	}
+
+	listener.err = fmt.Errorf("node down")
+	if s.update(); s.Error != "node down" {
+		t.Fatalf("expect error reported, got %q", s.Error)
+	}
+	listener.err = nil
+	if s.update(); s.Error != "" {
+		t.Fatalf("expect error cleared, got %q", s.Error)
+	}
+}
+
+func TestSyncStatusEstimate(t *testing.T) {
+	start := time.Now()
+	sample := func(tip, synced uint64, offset time.Duration) *SyncStatus {
+		s := &SyncStatus{Tip: tip, Synced: synced, time: start.Add(offset)}
+		if tip > synced {
+			s.Lag = tip - synced
+		}
+		return s
+	}
+	cases := []struct {
+		name    string
+		last    *SyncStatus
+		current *SyncStatus
+		catchUp float64
+	}{
+		// Lag shrinks by 10 blocks in 10 seconds
+		{"catching up", sample(100, 50, 0), sample(110, 70, 10*time.Second), 40},
+		{"falling behind", sample(100, 50, 0), sample(120, 60, 10*time.Second), 0},
+		{"synced", sample(100, 100, 0), sample(110, 110, 10*time.Second), 0},
+		{"same sample time", sample(100, 50, 0), sample(110, 70, 0), 0},
+	}
+	for _, c := range cases {
+		if c.current.estimate(c.last); c.current.CatchUp != c.catchUp {
+			t.Fatalf("%s: expect catch up %v seconds, got %v", c.name, c.catchUp, c.current.CatchUp)
+		}
+	}
+	failed := sample(110, 70, 10*time.Second)
+	failed.Error = "node down"
+	if failed.estimate(sample(100, 50, 0)); failed.CatchUp != 0 {
+		t.Fatal("expect no estimate on failed sample")
+	}
+}
+
+// Listener reading the pause flags of the light client contract
+type pausedListener struct {
+	*statusListener
+	flags uint64
+}
+
+func (l *pausedListener) PauseFlags() (uint64, error) { return l.flags, nil }
+
+// Submitter suspended while the light client contract is paused
+type pausedSubmitter struct {
+	*fakeSubmitter
+	flags uint64
+}
+
+func (s *pausedSubmitter) Suspended() uint64 { return s.flags }
+
+func TestSuspendedStatus(t *testing.T) {
+	listener := &pausedListener{statusListener: &statusListener{tip: 100, synced: 90}, flags: eth.PAUSED_ADD_BLOCK}
+	s := &SyncStatus{listener: listener}
+	if s.update(); !s.Suspended() || s.Flags != eth.PAUSED_ADD_BLOCK {
+		t.Fatalf("expect suspended status, got flags %d", s.Flags)
+	}
+	listener.flags = eth.PAUSED_DEPOSIT
+	if s.update(); s.Suspended() {
+		t.Fatal("expect active status with deposits paused only")
+	}
+
+	submitter := &pausedSubmitter{fakeSubmitter: &fakeSubmitter{}, flags: eth.PAUSED_ADD_BLOCK}
+	h := newTestHandler(t, &fakeListener{}, submitter, 0)
+	if status := h.Status(); !status.Suspended || status.Flags != eth.PAUSED_ADD_BLOCK || status.Paused {
+		t.Fatalf("expect header sync suspended, got %+v", status)
+	}
+	submitter.flags = 0
+	if status := h.Status(); status.Suspended {
+		t.Fatal("expect header sync resumed once unpaused")
+	}
+}
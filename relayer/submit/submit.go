@@ -0,0 +1,401 @@
+// Package submit runs the header submission loops shared by the submitters, the chain specific calls
+// are provided by the light client target of the submitter.
+package submit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/polynetwork/bridge-common/log"
+
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/metrics"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
+	"github.com/top/top-relayer/relayer/sender"
+	"github.com/top/top-relayer/store"
+)
+
+// Target is the light client contract the headers are submitted to
+type Target interface {
+	// Call data of the header submission
+	Pack(header *msg.Header) ([]byte, error)
+	GetSideChainHeight(chainId uint64) (height uint64, err error)
+	CheckHeaderExistence(header *msg.Header) (ok bool, err error)
+}
+
+// Suspender is implemented by targets suspending submission while the light client contract is paused
+type Suspender interface {
+	// Pause flags of the last poll if submission is suspended, otherwise zero
+	Suspended() (flags uint64)
+	// Poll the pause flags again, returns true if submission is suspended
+	CheckSuspended() bool
+	// Interval between polls of the pause flags
+	PauseCheck() time.Duration
+}
+
+// Max failed attempts to submit a batch before the header sync is reset
+const MAX_ATTEMPTS = 30
+
+// Wait between the failed attempts
+var retryWait = time.Second
+
+// Pipeline submits the headers of the channel in order, failures are reported to the header sync as resets
+type Pipeline struct {
+	context.Context
+	Target   Target
+	Name     string // target chain name
+	Config   *config.HeaderSyncConfig
+	Conn     *live.Conn
+	Sender   *sender.Pool
+	Metrics  *metrics.HeaderSync
+	Contract common.Address
+
+	state *store.State
+	reset chan<- msg.Reset
+	done  chan struct{} // closed when the sync loop exits
+	// Check last header commit
+	lastCommit uint64
+	lastCheck  uint64
+}
+
+// Start the sync loop with the default settings applied, the batch or single header loop is used if loop is nil
+func (p *Pipeline) Start(ctx context.Context, reset chan<- msg.Reset, state *store.State, loop func(<-chan msg.Header)) (ch chan msg.Header) {
+	p.Context = ctx
+	p.reset = reset
+	p.state = state
+
+	// Defaults are applied to the live settings, the shared config is kept as loaded for reload diffs
+	settings := p.Conn.Load().Merge(live.FromConfig(p.Config))
+	if settings.Batch <= 0 {
+		settings.Batch = 1
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 1
+	}
+	p.Conn.Store(settings)
+	buffer := p.Config.Buffer
+	if buffer == 0 {
+		buffer = 2 * settings.Batch
+	}
+	if loop == nil {
+		loop = p.syncHeaderBatchLoop
+		if settings.Batch == 1 {
+			loop = p.syncHeaderLoop
+		}
+	}
+	ch = make(chan msg.Header, buffer)
+	p.done = make(chan struct{})
+	go p.startSync(ch, loop)
+	return
+}
+
+// Stop waits for the sync loop to exit, it exits after the header channel is closed and drained,
+// or the sync context is done.
+func (p *Pipeline) Stop() error {
+	if p.done != nil {
+		<-p.done
+	}
+	return nil
+}
+
+func (p *Pipeline) SubmitHeadersWithLoop(chainId uint64, headers []msg.Header, header *msg.Header) (failed uint64, err error) {
+	start := time.Now()
+	h := uint64(0)
+	if len(headers) > 0 {
+		failed, err = p.submitHeadersWithLoop(chainId, headers)
+		if err == nil && header != nil {
+			// Check last commit every 4 successful submit
+			if p.lastCommit > 0 && p.lastCheck > 3 {
+				p.lastCheck = 0
+				height, e := p.Target.GetSideChainHeight(chainId)
+				if e != nil {
+					log.Error("Get side chain header height failure", "err", e)
+				} else if height < p.lastCommit {
+					log.Error("Chain header submit confirm check failure", "chain", p.Name, "height", height, "last_submit", p.lastCommit)
+					err = msg.ERR_HEADER_MISSING
+					failed = height + 1
+					p.Metrics.ConfirmChecks.Inc(1)
+					alert.Raise(
+						alert.WARN, fmt.Sprintf("confirm_check/%d/%d", p.Config.ChainId, p.Config.Submitter.ChainId),
+						"Header submit confirm check failure", "Light client height %d of %s is below last submit %d", height, p.Name, p.lastCommit,
+					)
+				} else {
+					log.Info("Chain header submit confirm check success", "chain", p.Name, "height", height, "last_submit", p.lastCommit)
+				}
+			} else {
+				p.lastCheck++
+			}
+		}
+	}
+	if header != nil {
+		h = header.Height
+		if err == nil {
+			p.lastCommit = header.Height // Mark last commit
+		}
+	}
+	if err != nil {
+		p.Metrics.SubmitFailure(err)
+	}
+	log.Info("Submit headers", "chain", chainId, "target", p.Name, "size", len(headers), "height", h, "elapse", time.Since(start), "failed", failed, "err", err)
+	return
+}
+
+// Submit the headers in order, returns the height of the first header failed to be submitted on error
+func (p *Pipeline) submitHeadersWithLoop(chainId uint64, headers []msg.Header) (uint64, error) {
+	suspender, _ := p.Target.(Suspender)
+	attempt := 0
+	for {
+		var err error
+		// Skip the headers already synced
+		for len(headers) > 0 {
+			var ok bool
+			ok, err = p.Target.CheckHeaderExistence(&headers[0])
+			if err != nil {
+				log.Error("Failed to check header existence", "chain", chainId, "height", headers[0].Height, "err", err)
+				attempt++
+				break
+			}
+			if !ok {
+				break
+			}
+			p.markSubmitted(&headers[0])
+			headers = headers[1:]
+		}
+		if len(headers) == 0 {
+			return 0, nil
+		}
+
+		suspended := suspender != nil && suspender.Suspended() != 0
+		if err == nil && !suspended {
+			attempt += 1
+			var count int
+			count, err = p.SubmitHeaders(chainId, headers)
+			headers = headers[count:]
+			if err == nil {
+				return 0, nil
+			}
+			typed := sender.ClassifyError(err)
+			if typed == msg.ERR_CONTRACT_PAUSED && suspender != nil && suspender.CheckSuspended() {
+				// Wait for the contract to be unpaused instead of giving up
+				suspended = true
+				attempt--
+			} else {
+				switch msg.SubmitPolicy(typed) {
+				case msg.POLICY_SKIP:
+					log.Info("Header already accepted by light client", "chain", chainId, "height", headers[0].Height, "err", err)
+					p.markSubmitted(&headers[0])
+					headers = headers[1:]
+					continue
+				case msg.POLICY_ROLLBACK:
+					//NOTE: reset header height back here
+					log.Error("Possible hard fork, will rollback some blocks", "chain", chainId, "height", headers[0].Height, "err", err)
+					return headers[0].Height, typed
+				case msg.POLICY_GIVE_UP:
+					log.Error("Header submit can not proceed, backing off", "chain", chainId, "height", headers[0].Height, "err", err)
+					return headers[0].Height, typed
+				}
+				log.Error("Failed to submit header", "chain", chainId, "target", p.Name, "height", headers[0].Height, "err", err)
+			}
+		}
+		wait := retryWait
+		if suspended {
+			// Submission is suspended until the contract is unpaused, not counted as failed attempts
+			log.Warn("Header submission suspended while bridge is paused", "chain", chainId, "height", headers[0].Height, "flags", suspender.Suspended())
+			wait = suspender.PauseCheck()
+		} else if err == msg.ERR_LOW_BALANCE {
+			// Submission is paused until the accounts are funded, not counted as failed attempts
+			log.Error("Header submission paused for low balance", "chain", chainId, "height", headers[0].Height)
+			p.Metrics.SubmitFailure(err)
+			attempt--
+			wait = 30 * time.Second
+		}
+		select {
+		case <-p.Done():
+			log.Warn("Header submitter exiting with headers not submitted", "chain", chainId, "height", headers[0].Height)
+			return headers[0].Height, p.Err()
+		case <-time.After(wait):
+			if attempt > MAX_ATTEMPTS {
+				log.Error("Header submit too many failed attempts", "chain", chainId, "attempts", attempt)
+				return headers[0].Height, msg.ERR_HEADER_SUBMIT_FAILURE
+			}
+		}
+	}
+}
+
+// Submit headers with one tx per header, txs are sent with consecutive nonces before waiting for confirmations.
+// Returns the count of leading headers confirmed successfully.
+func (p *Pipeline) SubmitHeaders(chainId uint64, headers []msg.Header) (count int, err error) {
+	txs := []*types.Transaction{}
+	start := time.Now()
+	var limit uint64
+	for _, header := range headers {
+		data, e := p.Target.Pack(&header)
+		if e != nil {
+			err = fmt.Errorf("Pack header at height %v error %v", header.Height, e)
+			break
+		}
+		tx, e := p.Sender.Send(p.Context, p.Contract, data, limit)
+		if e != nil {
+			err = e
+			break
+		}
+		// Later headers depend on the earlier ones, so reuse the estimated limit
+		limit = tx.Gas()
+		txs = append(txs, tx)
+		if p.state != nil {
+			p.state.AddPending(header.Height, tx.Hash().String())
+		}
+	}
+
+	failed := false
+	for i, tx := range txs {
+		if !failed {
+			_, e := p.Sender.Confirm(p.Context, tx.Hash())
+			if e == nil {
+				count++
+				p.Metrics.Submitted.Inc(1)
+				p.Metrics.Latency.Update(time.Since(start).Milliseconds())
+				p.markSubmitted(&headers[i])
+				log.Info("Submitted header", "chain", chainId, "target", p.Name, "height", headers[i].Height, "hash", tx.Hash().String())
+				continue
+			}
+			err = e
+			failed = true
+			p.Sender.Reset()
+			if e == msg.ERR_TX_TIMEOUT {
+				// The stuck nonce blocks the later ones, release the account for the resubmission
+				for _, obsolete := range txs[i:] {
+					p.Sender.Cancel(p.Context, obsolete.Hash())
+				}
+			} else if sender.ClassifyError(e) != nil {
+				// Later headers can not be accepted after a reverted one
+				for _, obsolete := range txs[i+1:] {
+					p.Sender.Cancel(p.Context, obsolete.Hash())
+				}
+			}
+		}
+		if p.state != nil {
+			p.state.RemovePending(headers[i].Height)
+		}
+	}
+	return
+}
+
+func (p *Pipeline) markSubmitted(header *msg.Header) {
+	if p.state == nil {
+		return
+	}
+	err := p.state.MarkSubmitted(header.Height, header.Hash)
+	if err != nil {
+		log.Error("Failed to record submitted header", "chain", p.Config.ChainId, "height", header.Height, "err", err)
+	}
+}
+
+// Wait for the submission txs left pending by last run, so the headers wont be submitted twice
+func (p *Pipeline) confirmPending() {
+	txs, err := p.state.Pending()
+	if err != nil {
+		log.Error("Failed to read pending header txs", "chain", p.Config.ChainId, "err", err)
+		return
+	}
+	for height, hash := range txs {
+		_, err = p.Sender.Confirm(p.Context, common.HexToHash(hash))
+		log.Info("Confirming pending header tx of last run", "chain", p.Config.ChainId, "height", height, "hash", hash, "err", err)
+		p.state.RemovePending(height)
+	}
+}
+
+func (p *Pipeline) syncHeaderLoop(ch <-chan msg.Header) {
+	for {
+		select {
+		case <-p.Done():
+			return
+		case header, ok := <-ch:
+			if !ok {
+				return
+			}
+			// NOTE err reponse here will revert header sync to the first failed height
+			headers := []msg.Header{header}
+			if header.Data == nil {
+				headers = nil
+			}
+			failed, err := p.SubmitHeadersWithLoop(p.Config.ChainId, headers, &header)
+			if err != nil {
+				p.Reset(failed, err)
+			}
+		}
+	}
+}
+
+func (p *Pipeline) syncHeaderBatchLoop(ch <-chan msg.Header) {
+	headers := []msg.Header{}
+	commit := false
+	var hdr *msg.Header
+
+COMMIT:
+	for {
+		select {
+		case <-p.Done():
+			break COMMIT
+		case header, ok := <-ch:
+			if ok {
+				hdr = &header
+				if hdr.Data == nil {
+					// Update header sync height
+					commit = true
+				} else {
+					headers = append(headers, header)
+					commit = len(headers) >= p.Conn.Load().Batch
+				}
+			} else {
+				// Flush the buffered headers before exiting
+				if len(headers) > 0 {
+					failed, err := p.SubmitHeadersWithLoop(p.Config.ChainId, headers, hdr)
+					if err == nil {
+						headers = nil
+					} else {
+						log.Error("Failed to flush buffered headers", "chain", p.Config.ChainId, "failed", failed, "err", err)
+					}
+				}
+				break COMMIT
+			}
+		case <-time.After(time.Duration(p.Conn.Load().Timeout) * time.Second):
+			commit = len(headers) > 0
+		}
+		if commit {
+			commit = false
+			// NOTE err reponse here will revert header sync to the first failed height
+			failed, err := p.SubmitHeadersWithLoop(p.Config.ChainId, headers, hdr)
+			if err != nil {
+				p.Reset(failed, err)
+			}
+			headers = []msg.Header{}
+		}
+	}
+	if len(headers) > 0 {
+		log.Warn("Header sync aborted with headers not submitted", "chain", p.Config.ChainId, "from", headers[0].Height, "size", len(headers))
+	}
+}
+
+func (p *Pipeline) startSync(ch <-chan msg.Header, loop func(<-chan msg.Header)) {
+	defer close(p.done)
+	if p.state != nil {
+		p.confirmPending()
+	}
+	loop(ch)
+	log.Info("Header sync exiting loop now", "chain", p.Config.ChainId, "target", p.Name)
+}
+
+// Reset sends the reset request unless the submitter is exiting
+func (p *Pipeline) Reset(height uint64, err error) {
+	select {
+	case p.reset <- msg.Reset{Height: height, Err: err}:
+	case <-p.Done():
+	}
+}
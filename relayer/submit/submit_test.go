@@ -0,0 +1,172 @@
+package submit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/metrics"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
+	"github.com/top/top-relayer/store"
+)
+
+// Light client target, headers below the height exist
+type fakeTarget struct {
+	height uint64
+	err    error
+	checks int
+}
+
+func (t *fakeTarget) Pack(header *msg.Header) ([]byte, error) {
+	return header.Data, nil
+}
+
+func (t *fakeTarget) GetSideChainHeight(chainId uint64) (uint64, error) {
+	return t.height, nil
+}
+
+func (t *fakeTarget) CheckHeaderExistence(header *msg.Header) (bool, error) {
+	t.checks++
+	return header.Height <= t.height, t.err
+}
+
+// Target with the light client contract paused
+type pausedTarget struct {
+	*fakeTarget
+}
+
+func (t *pausedTarget) Suspended() uint64         { return 4 }
+func (t *pausedTarget) CheckSuspended() bool      { return true }
+func (t *pausedTarget) PauseCheck() time.Duration { return time.Millisecond }
+
+func testPipeline(t *testing.T, target Target, timeout time.Duration) *Pipeline {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	t.Cleanup(cancel)
+	wait := retryWait
+	retryWait = time.Millisecond
+	t.Cleanup(func() { retryWait = wait })
+	return &Pipeline{
+		Context: ctx, Target: target, Name: "test",
+		Config:  &config.HeaderSyncConfig{Submitter: &config.SubmitterConfig{ChainId: base.TOP}, ListenerConfig: &config.ListenerConfig{ChainId: base.ETH}},
+		Metrics: metrics.ForHeaderSync(base.ETH, base.TOP),
+	}
+}
+
+func headers(from, to uint64) (list []msg.Header) {
+	for height := from; height <= to; height++ {
+		list = append(list, msg.Header{Height: height, Data: []byte{byte(height)}})
+	}
+	return
+}
+
+func TestSubmitExistingHeaders(t *testing.T) {
+	target := &fakeTarget{height: 5}
+	p := testPipeline(t, target, time.Second)
+	failed, err := p.submitHeadersWithLoop(base.ETH, headers(3, 5))
+	if failed != 0 || err != nil {
+		t.Fatalf("expect headers accepted already skipped, got %d %v", failed, err)
+	}
+	if target.checks != 3 {
+		t.Fatalf("expect 3 existence checks, got %d", target.checks)
+	}
+}
+
+func TestExistenceCheckFailures(t *testing.T) {
+	target := &fakeTarget{err: errors.New("node down")}
+	p := testPipeline(t, target, 10*time.Second)
+	failed, err := p.submitHeadersWithLoop(base.ETH, headers(3, 5))
+	if failed != 3 || err != msg.ERR_HEADER_SUBMIT_FAILURE {
+		t.Fatalf("expect submit failure at 3 after too many attempts, got %d %v", failed, err)
+	}
+	if target.checks != MAX_ATTEMPTS+1 {
+		t.Fatalf("expect existence check failures counted as attempts, got %d checks", target.checks)
+	}
+}
+
+func TestSuspendedSubmission(t *testing.T) {
+	// Submission is never attempted while suspended, and the suspension is not counted as failures
+	target := &pausedTarget{&fakeTarget{}}
+	p := testPipeline(t, target, 200*time.Millisecond)
+	failed, err := p.submitHeadersWithLoop(base.ETH, headers(3, 5))
+	if failed != 3 || err != context.DeadlineExceeded {
+		t.Fatalf("expect suspended until exit, got %d %v", failed, err)
+	}
+	if target.checks <= MAX_ATTEMPTS+1 {
+		t.Fatalf("expect suspension polled without giving up, got %d checks", target.checks)
+	}
+}
+
+func TestStopFlushesBufferedHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relayer-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	state := db.State(base.ETH, base.TOP)
+
+	target := &fakeTarget{height: 10}
+	p := testPipeline(t, target, 10*time.Second)
+	p.Conn = &live.Conn{}
+	p.Config.Batch, p.Config.Timeout = 4, 60
+	ch := p.Start(p.Context, make(chan msg.Reset, 1), state, nil)
+	// Less than a batch, the headers are only submitted on flush
+	for _, header := range headers(1, 3) {
+		ch <- header
+	}
+	close(ch)
+	if err = p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if target.checks != 3 {
+		t.Fatalf("expect buffered headers flushed on stop, got %d checks", target.checks)
+	}
+	if height, _ := state.SubmitHeight(); height != 3 {
+		t.Fatalf("expect progress recorded at 3, got %d", height)
+	}
+}
+
+// Target unpaused after the polls, the headers are accepted by then
+type unpausedTarget struct {
+	*fakeTarget
+	polls int
+}
+
+func (t *unpausedTarget) Suspended() uint64 {
+	if t.polls > 0 {
+		return 4
+	}
+	return 0
+}
+
+func (t *unpausedTarget) CheckSuspended() bool { return t.Suspended() != 0 }
+
+func (t *unpausedTarget) PauseCheck() time.Duration {
+	if t.polls--; t.polls == 0 {
+		t.height = 5
+	}
+	return time.Millisecond
+}
+
+func TestResumeAfterUnpause(t *testing.T) {
+	target := &unpausedTarget{fakeTarget: &fakeTarget{}, polls: 3}
+	p := testPipeline(t, target, time.Second)
+	failed, err := p.submitHeadersWithLoop(base.ETH, headers(3, 5))
+	if failed != 0 || err != nil {
+		t.Fatalf("expect submission resumed once unpaused, got %d %v", failed, err)
+	}
+	if target.polls != 0 {
+		t.Fatalf("expect suspended until unpaused, %d polls left", target.polls)
+	}
+}
@@ -13,11 +13,12 @@ import (
 	"github.com/top/top-relayer/abi/bridge"
 	"github.com/top/top-relayer/base"
 	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/relayer/live"
 )
 
 type Listener struct {
-	sdk        *ethcommon.SDK
-	peer       *ethcommon.SDK
+	conn       *live.Conn
 	hscontract common.Address
 	config     *config.HeaderSyncConfig
 	name       string
@@ -29,14 +30,14 @@ func (l *Listener) Init(config *config.HeaderSyncConfig, peerSdk *ethcommon.SDK)
 	}
 
 	l.config = config
-	l.sdk, err = ethcommon.WithOptions(config.ChainId, config.Nodes, time.Minute, 1)
+	l.conn, err = live.Dial(config.ChainId, config.Nodes, live.FromConfig(config))
 	if err != nil {
 		return fmt.Errorf("fail to init sdk, err is %s", err.Error())
 	}
 
 	l.hscontract = common.HexToAddress(config.Submitter.HSContract)
 
-	l.peer = peerSdk
+	l.conn.SetPeer(peerSdk)
 	return nil
 }
 
@@ -48,33 +49,42 @@ func (l *Listener) Defer() int {
 	return 1
 }
 
-func (l *Listener) Header(height uint64) (header []byte, hash []byte, err error) {
-	hdr, err := l.sdk.Node().HeaderByNumber(context.Background(), big.NewInt(int64(height)))
+func (l *Listener) Header(height uint64) (header *msg.Header, err error) {
+	hdr, err := l.conn.SDK().Node().HeaderByNumber(context.Background(), big.NewInt(int64(height)))
 	if err != nil {
 		err = fmt.Errorf("Fetch block header error %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 	log.Info("Fetched block header", "chain", l.name, "height", height, "hash", hdr.Hash().String())
-	hash = hdr.Hash().Bytes()
-	header, err = hdr.MarshalJSON()
+	header = &msg.Header{Height: height, Hash: hdr.Hash().Bytes(), Parent: hdr.ParentHash.Bytes(), Time: hdr.Time}
+	header.Data, err = hdr.MarshalJSON()
+	return
+}
+
+// Reload applies the node list and listen check changes in place
+func (l *Listener) Reload(config *config.HeaderSyncConfig, peerSdk *ethcommon.SDK) (err error) {
+	err = l.conn.Reload(config.Nodes, live.FromConfig(config))
+	if err == nil {
+		l.conn.SetPeer(peerSdk)
+	}
 	return
 }
 
 func (l *Listener) ListenCheck() time.Duration {
 	duration := time.Second
-	if l.config.ListenCheck > 0 {
-		duration = time.Duration(l.config.ListenCheck) * time.Second
+	if check := l.conn.Load().ListenCheck; check > 0 {
+		duration = time.Duration(check) * time.Second
 	}
 	return duration
 }
 
 func (l *Listener) Nodes() chains.Nodes {
-	return l.sdk.ChainSDK
+	return l.conn.SDK().ChainSDK
 }
 
 func (l *Listener) LastHeaderSync(force, last uint64) (height uint64, err error) {
-	if l.peer == nil {
-		err = fmt.Errorf("No poly sdk provided for listener", "chain", l.name)
+	if l.conn.Peer() == nil {
+		err = fmt.Errorf("No poly sdk provided for listener of chain %s", l.name)
 		return
 	}
 
@@ -87,7 +97,7 @@ func (l *Listener) LastHeaderSync(force, last uint64) (height uint64, err error)
 
 //todo
 func (l *Listener) getSideChainHeight(chainId uint64) (height uint64, err error) {
-	hscaller, err := bridge.NewBridgeCaller(l.hscontract, l.sdk.Node())
+	hscaller, err := bridge.NewBridgeCaller(l.hscontract, l.conn.Peer().Node())
 	if err != nil {
 		return 0, fmt.Errorf("Proccess: fail to get side chain height by chain id %d", chainId)
 	}
@@ -96,6 +106,23 @@ func (l *Listener) getSideChainHeight(chainId uint64) (height uint64, err error)
 	return
 }
 
+// PauseFlags reads the pause flags of the light client contract on the peer chain
+func (l *Listener) PauseFlags() (flags uint64, err error) {
+	if l.conn.Peer() == nil {
+		err = fmt.Errorf("No peer sdk provided for listener of chain %s", l.name)
+		return
+	}
+	caller, err := bridge.NewBridgeCaller(l.hscontract, l.conn.Peer().Node())
+	if err != nil {
+		return
+	}
+	paused, err := caller.Paused(nil)
+	if err != nil {
+		return
+	}
+	return paused.Uint64(), nil
+}
+
 func (l *Listener) LatestHeight() (uint64, error) {
-	return l.sdk.Node().GetLatestHeight()
+	return l.conn.SDK().Node().GetLatestHeight()
 }
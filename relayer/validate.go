@@ -0,0 +1,110 @@
+package relayer
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/polynetwork/bridge-common/wallet"
+
+	"github.com/top/top-relayer/alert"
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/relayer/sender"
+)
+
+// Timeout of node chain id probe
+const PROBE_TIMEOUT = 10 * time.Second
+
+// Validate the initialized config, wallets are decrypted and nodes are probed for chain id if probe is set
+func Validate(c *config.Config, probe bool) (problems config.Problems) {
+	problems = c.Validate()
+	if _, err := alert.New(c.Alert); err != nil {
+		problems.Add("Alert: %v", err)
+	}
+
+	ids := []uint64{}
+	for id := range c.Chains {
+		if c.Active(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	nodes := map[string]uint64{}
+	for _, id := range ids {
+		for _, hs := range c.Chains[id].HeaderSync {
+			if hs == nil || !hs.Enabled || hs.ListenerConfig == nil || hs.Submitter == nil {
+				continue
+			}
+			name := base.GetChainName(hs.ChainId) + " -> " + base.GetChainName(hs.Submitter.ChainId)
+			if GetListener(hs.ChainId) == nil {
+				problems.Add("Header sync %s: no listener for chain %d", name, hs.ChainId)
+			}
+			if GetSubmitter(hs.Submitter.ChainId) == nil {
+				problems.Add("Header sync %s: no submitter for chain %d", name, hs.Submitter.ChainId)
+			}
+			if _, err := sender.NewGasStrategy(hs.Submitter.Gas); err != nil {
+				problems.Add("Header sync %s: %v", name, err)
+			}
+			switch hs.Submitter.Select {
+			case "", sender.SELECT_ROUND_ROBIN, sender.SELECT_LEAST_BUSY:
+			default:
+				problems.Add("Header sync %s: unknown account selection %s", name, hs.Submitter.Select)
+			}
+			for _, node := range hs.Nodes {
+				nodes[node] = hs.ChainId
+			}
+			for _, node := range hs.Submitter.Nodes {
+				nodes[node] = hs.Submitter.ChainId
+			}
+			if hs.Submitter.Wallet == nil {
+				continue
+			}
+			for _, node := range hs.Submitter.Wallet.Nodes {
+				nodes[node] = hs.Submitter.ChainId
+			}
+			if probe {
+				// Accounts are decrypted without chain id verification, nodes are probed below
+				if err := wallet.New(hs.Submitter.Wallet, nil).Init(); err != nil {
+					problems.Add("Header sync %s: wallet %v", name, err)
+				}
+			}
+		}
+	}
+
+	if !probe {
+		return
+	}
+	urls := []string{}
+	for url := range nodes {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		chain := nodes[url]
+		id, err := probeChainId(url)
+		if err != nil {
+			problems.Add("Chain %s node %s: %v", base.GetChainName(chain), url, err)
+		} else if expected := base.NetworkId(chain); expected != 0 && id != expected {
+			problems.Add("Chain %s node %s: chain id %d, expected %d", base.GetChainName(chain), url, id, expected)
+		}
+	}
+	return
+}
+
+func probeChainId(url string) (id uint64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), PROBE_TIMEOUT)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return
+	}
+	return chainId.Uint64(), nil
+}
@@ -0,0 +1,124 @@
+package relayer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"time"
+
+	"github.com/polynetwork/bridge-common/log"
+	"github.com/top/top-relayer/msg"
+)
+
+// Default size of the fetched header window used to detect source chain reorgs
+const HEADER_WINDOW = 256
+
+// HeaderWindow keeps the hashes of recently fetched consecutive headers
+type HeaderWindow struct {
+	size    int
+	headers []msg.Header // Data is not kept
+}
+
+func NewHeaderWindow(size int) *HeaderWindow {
+	if size <= 0 {
+		size = HEADER_WINDOW
+	}
+	return &HeaderWindow{size: size}
+}
+
+// Get the header hashes at height
+func (w *HeaderWindow) Get(height uint64) (header msg.Header, ok bool) {
+	if len(w.headers) == 0 {
+		return
+	}
+	first := w.headers[0].Height
+	if height < first || height >= first+uint64(len(w.headers)) {
+		return
+	}
+	return w.headers[height-first], true
+}
+
+// Push the next header, the window is restarted if the header does not follow the last one
+func (w *HeaderWindow) Push(header *msg.Header) {
+	if n := len(w.headers); n > 0 && w.headers[n-1].Height+1 != header.Height {
+		w.headers = nil
+	}
+	w.headers = append(w.headers, msg.Header{Height: header.Height, Hash: header.Hash, Parent: header.Parent})
+	if len(w.headers) > w.size {
+		w.headers = w.headers[len(w.headers)-w.size:]
+	}
+}
+
+// Truncate the headers above height
+func (w *HeaderWindow) Truncate(height uint64) {
+	for len(w.headers) > 0 && w.headers[len(w.headers)-1].Height > height {
+		w.headers = w.headers[:len(w.headers)-1]
+	}
+}
+
+// Lowest height in window, zero if empty
+func (w *HeaderWindow) First() uint64 {
+	if len(w.headers) == 0 {
+		return 0
+	}
+	return w.headers[0].Height
+}
+
+// Check if the header extends the window
+func (w *HeaderWindow) Links(header *msg.Header) bool {
+	last, ok := w.Get(header.Height - 1)
+	return !ok || bytes.Equal(last.Hash, header.Parent)
+}
+
+// checkSourceReorg walks back from the fetched header along parent hashes until it joins the window.
+// Returns the common ancestor height, and false if the header extends the window or the handler exits.
+// If the fork point is below the window, the common ancestor is searched against the light client.
+// Both ways the ancestor is moved back below the epoch start for chains rotating validators by epoch.
+func (h *HeaderSyncHandler) checkSourceReorg(header *msg.Header) (ancestor uint64, reorg bool) {
+	if h.window.Links(header) {
+		return
+	}
+
+	report := &Reorg{Chain: h.config.ChainId, Target: h.config.Submitter.ChainId, Height: header.Height, Time: time.Now()}
+	branch := []*msg.Header{header}
+	cur := header
+	for {
+		parent, ok := h.window.Get(cur.Height - 1)
+		if !ok {
+			// Forked below the window, search the common ancestor with light client
+			log.Warn("Source chain forked below the header window", "chain", h.config.ChainId, "height", header.Height)
+			target := h.window.First()
+			if target > 0 {
+				target--
+			}
+			return h.RollbackToCommonAncestor(header.Height, target), true
+		}
+		if bytes.Equal(parent.Hash, cur.Parent) {
+			ancestor = parent.Height
+			break
+		}
+		var next *msg.Header
+		err := h.retry(func() (err error) {
+			next, err = h.listener.Header(cur.Height - 1)
+			return
+		})
+		if err != nil {
+			return 0, false
+		}
+		branch = append(branch, next)
+		cur = next
+	}
+
+	report.Ancestor = ancestor
+	report.Depth = header.Height - ancestor - 1
+	for i := len(branch) - 1; i >= 0 && len(report.New) < REORG_REPORT_SIZE; i-- {
+		report.New = append(report.New, msg.BlockHash{Height: branch[i].Height, Hash: "0x" + hex.EncodeToString(branch[i].Hash)})
+	}
+	for height := ancestor + 1; height < header.Height && len(report.Old) < REORG_REPORT_SIZE; height++ {
+		if old, ok := h.window.Get(height); ok {
+			report.Old = append(report.Old, msg.BlockHash{Height: height, Hash: "0x" + hex.EncodeToString(old.Hash)})
+		}
+	}
+	log.Warn("Detected source chain reorg before submission", "chain", h.config.ChainId, "height", header.Height, "ancestor", ancestor)
+	h.reportReorg(report)
+	return h.epochStart(ancestor), true
+}
@@ -0,0 +1,224 @@
+package relayer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/top/top-relayer/base"
+	"github.com/top/top-relayer/config"
+	"github.com/top/top-relayer/msg"
+	"github.com/top/top-relayer/store"
+)
+
+// Block hash of the branch at height, branches share the blocks up to the fork height
+func blockHash(branch byte, fork, height uint64) []byte {
+	hash := make([]byte, 32)
+	hash[0] = 'a'
+	if height > fork {
+		hash[0] += branch
+	}
+	binary.BigEndian.PutUint64(hash[24:], height)
+	return hash
+}
+
+func branchHashes(branch byte, fork, tip uint64) map[uint64][]byte {
+	hashes := map[uint64][]byte{}
+	for height := uint64(0); height <= tip; height++ {
+		hashes[height] = blockHash(branch, fork, height)
+	}
+	return hashes
+}
+
+// Source chain serving headers of the branch
+type fakeListener struct {
+	IChainListener
+	hashes map[uint64][]byte
+	calls  int
+}
+
+func (l *fakeListener) Header(height uint64) (header *msg.Header, err error) {
+	l.calls++
+	hash, ok := l.hashes[height]
+	if !ok {
+		return nil, fmt.Errorf("Header %d not found", height)
+	}
+	return &msg.Header{Height: height, Hash: hash, Parent: l.hashes[height-1]}, nil
+}
+
+// Light client with the block hashes accepted on target chain
+type fakeSubmitter struct {
+	IChainSubmitter
+	hashes map[uint64][]byte
+	calls  int
+}
+
+func (s *fakeSubmitter) GetSideChainHeader(chainId, height uint64) (hash []byte, err error) {
+	s.calls++
+	hash, ok := s.hashes[height]
+	if !ok {
+		hash = make([]byte, 32)
+	}
+	return
+}
+
+func newTestHandler(t *testing.T, listener IChainListener, submitter IChainSubmitter, window int) *HeaderSyncHandler {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "relayer-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	conf := &config.HeaderSyncConfig{
+		HeaderWindow:   window,
+		Submitter:      &config.SubmitterConfig{ChainId: base.TOP},
+		ListenerConfig: &config.ListenerConfig{ChainId: base.ETH},
+	}
+	h := NewHeaderSyncHandler(conf, db.State(base.ETH, base.TOP))
+	h.listener, h.submitter = listener, submitter
+	var cancel context.CancelFunc
+	h.Context, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return h
+}
+
+func TestHeaderWindowEviction(t *testing.T) {
+	cases := []struct {
+		name    string
+		size    int
+		heights []uint64
+		first   uint64
+		count   int
+	}{
+		{"empty", 4, nil, 0, 0},
+		{"partial", 4, []uint64{1, 2, 3}, 1, 3},
+		{"full", 4, []uint64{1, 2, 3, 4}, 1, 4},
+		{"evicts oldest", 4, []uint64{1, 2, 3, 4, 5, 6}, 3, 4},
+		{"restarts on gap", 4, []uint64{1, 2, 3, 7, 8}, 7, 2},
+		{"default size", 0, []uint64{1, 2}, 1, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := NewHeaderWindow(c.size)
+			for _, height := range c.heights {
+				w.Push(&msg.Header{Height: height, Hash: blockHash(0, 0, height), Parent: blockHash(0, 0, height-1)})
+			}
+			if w.First() != c.first {
+				t.Fatalf("expect first %d, got %d", c.first, w.First())
+			}
+			if len(w.headers) != c.count {
+				t.Fatalf("expect %d headers, got %d", c.count, len(w.headers))
+			}
+			for _, height := range c.heights {
+				_, ok := w.Get(height)
+				expect := c.count > 0 && height >= c.first && height < c.first+uint64(c.count)
+				if ok != expect {
+					t.Fatalf("height %d in window %v, expect %v", height, ok, expect)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderWindowTruncate(t *testing.T) {
+	w := NewHeaderWindow(8)
+	for height := uint64(1); height <= 6; height++ {
+		w.Push(&msg.Header{Height: height, Hash: blockHash(0, 0, height), Parent: blockHash(0, 0, height-1)})
+	}
+	w.Truncate(4)
+	if _, ok := w.Get(5); ok {
+		t.Fatal("expect height 5 truncated")
+	}
+	if _, ok := w.Get(4); !ok {
+		t.Fatal("expect height 4 kept")
+	}
+	if !w.Links(&msg.Header{Height: 5, Parent: blockHash(0, 0, 4)}) {
+		t.Fatal("expect header 5 to extend the window after truncate")
+	}
+}
+
+func TestCheckSourceReorg(t *testing.T) {
+	const tip = 20
+	cases := []struct {
+		name     string
+		window   int
+		fork     uint64 // height the source chain forked from the window branch, tip if not forked
+		reorg    bool
+		ancestor uint64
+		depth    uint64
+	}{
+		{"extends window", 8, tip, false, 0, 0},
+		{"replaced tip", 8, tip - 1, true, tip - 1, 1},
+		{"forked in window", 8, tip - 5, true, tip - 5, 5},
+		{"forked at window start", 8, tip - 7, true, tip - 7, 7},
+		{"forked below window", 8, tip - 12, true, tip - 12, 12},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Window and light client follow branch 0, the source chain switched to branch 1
+			listener := &fakeListener{hashes: branchHashes(1, c.fork, tip+1)}
+			submitter := &fakeSubmitter{hashes: branchHashes(0, 0, tip)}
+			h := newTestHandler(t, listener, submitter, c.window)
+			for height := uint64(1); height <= tip; height++ {
+				h.window.Push(&msg.Header{Height: height, Hash: blockHash(0, 0, height), Parent: blockHash(0, 0, height-1)})
+			}
+			header, _ := listener.Header(tip + 1)
+			ancestor, reorg := h.checkSourceReorg(header)
+			if reorg != c.reorg {
+				t.Fatalf("expect reorg %v, got %v", c.reorg, reorg)
+			}
+			if ancestor != c.ancestor {
+				t.Fatalf("expect ancestor %d, got %d", c.ancestor, ancestor)
+			}
+			if !reorg {
+				return
+			}
+			report, _ := h.lastReorg.Load().(*Reorg)
+			if report == nil {
+				t.Fatal("expect reorg reported")
+			}
+			if report.Depth != c.depth {
+				t.Fatalf("expect reorg depth %d, got %d", c.depth, report.Depth)
+			}
+		})
+	}
+}
+
+func TestCheckSourceReorgEpoch(t *testing.T) {
+	const tip = 410 // epoch of bsc is 200
+	cases := []struct {
+		name     string
+		fork     uint64
+		ancestor uint64
+	}{
+		{"forked in window", tip - 5, 399},
+		{"forked below window", tip - 20, 199},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			listener := &fakeListener{hashes: branchHashes(1, c.fork, tip+1)}
+			submitter := &fakeSubmitter{hashes: branchHashes(0, 0, tip)}
+			h := newTestHandler(t, listener, submitter, 8)
+			h.config.ChainId = base.BSC
+			for height := uint64(1); height <= tip; height++ {
+				h.window.Push(&msg.Header{Height: height, Hash: blockHash(0, 0, height), Parent: blockHash(0, 0, height-1)})
+			}
+			header, _ := listener.Header(tip + 1)
+			ancestor, reorg := h.checkSourceReorg(header)
+			if !reorg || ancestor != c.ancestor {
+				t.Fatalf("expect rollback below epoch start to %d, got %d %v", c.ancestor, ancestor, reorg)
+			}
+		})
+	}
+}
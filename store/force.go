@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Forced header sync heights are kept in a file beside the state store, so that they can be set
+// by command while the running relayer holds the store lock. The read-modify-write of the file is
+// serialized across processes with a file lock.
+func forcePath(path string) string {
+	return path + ".force.json"
+}
+
+func forceKey(chain, dst uint64) string {
+	return fmt.Sprintf("%d/%d", chain, dst)
+}
+
+func readForce(path string) (heights map[string]uint64, err error) {
+	heights = map[string]uint64{}
+	data, err := ioutil.ReadFile(forcePath(path))
+	if os.IsNotExist(err) {
+		return heights, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &heights)
+	return
+}
+
+func writeForce(path string, heights map[string]uint64) (err error) {
+	if len(heights) == 0 {
+		err = os.Remove(forcePath(path))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	data, err := json.MarshalIndent(heights, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := forcePath(path) + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp, forcePath(path))
+}
+
+// SetForceHeight records the forced header sync height of the direction from chain to the dst chain,
+// header sync will resume from the next height.
+func SetForceHeight(path string, chain, dst, height uint64) (err error) {
+	unlock, err := lockForce(path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	heights, err := readForce(path)
+	if err != nil {
+		return
+	}
+	heights[forceKey(chain, dst)] = height
+	return writeForce(path, heights)
+}
+
+// ForceHeight returns the forced header sync height, zero if not set
+func (s *State) ForceHeight() (height uint64, err error) {
+	unlock, err := lockForce(s.path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	heights, err := readForce(s.path)
+	if err != nil {
+		return
+	}
+	return heights[forceKey(s.chain, s.dst)], nil
+}
+
+// ClearForceHeight removes the forced height once applied, unless it was changed meanwhile
+func (s *State) ClearForceHeight(height uint64) (err error) {
+	unlock, err := lockForce(s.path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	heights, err := readForce(s.path)
+	if err != nil {
+		return
+	}
+	key := forceKey(s.chain, s.dst)
+	if heights[key] != height {
+		return
+	}
+	delete(heights, key)
+	return writeForce(s.path, heights)
+}
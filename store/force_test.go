@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestForceHeight(t *testing.T) {
+	s := openStore(t)
+	state, other := s.State(1, 0), s.State(0, 1)
+
+	if err := SetForceHeight(s.path, 1, 0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if height, err := state.ForceHeight(); err != nil || height != 100 {
+		t.Fatalf("expect forced height 100, got %d %v", height, err)
+	}
+	if height, _ := other.ForceHeight(); height != 0 {
+		t.Fatalf("expect no forced height of the other direction, got %d", height)
+	}
+
+	// Changed by command meanwhile, the new height is kept
+	SetForceHeight(s.path, 1, 0, 200)
+	if err := state.ClearForceHeight(100); err != nil {
+		t.Fatal(err)
+	}
+	if height, _ := state.ForceHeight(); height != 200 {
+		t.Fatalf("expect changed forced height kept, got %d", height)
+	}
+	if err := state.ClearForceHeight(200); err != nil {
+		t.Fatal(err)
+	}
+	if height, _ := state.ForceHeight(); height != 0 {
+		t.Fatalf("expect forced height cleared, got %d", height)
+	}
+	if _, err := os.Stat(forcePath(s.path)); !os.IsNotExist(err) {
+		t.Fatalf("expect empty force height file removed, got %v", err)
+	}
+}
+
+func TestForceHeightLock(t *testing.T) {
+	s := openStore(t)
+	unlock, err := lockForce(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- SetForceHeight(s.path, 1, 0, 100)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expect force height write blocked by the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if height, _ := s.State(1, 0).ForceHeight(); height != 100 {
+		t.Fatalf("expect forced height written after unlock, got %d", height)
+	}
+}
@@ -0,0 +1,11 @@
+package store
+
+import "sync"
+
+var forceLock sync.Mutex
+
+// File locks are not supported, the force height file is only guarded within the process
+func lockForce(path string) (unlock func(), err error) {
+	forceLock.Lock()
+	return forceLock.Unlock, nil
+}
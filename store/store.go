@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// Count of recent header hashes kept per direction
+	HASH_WINDOW = 1000
+	// Count of recent reset events kept per direction
+	RESET_WINDOW = 100
+)
+
+const (
+	KEY_FETCH_HEIGHT  = "fetch"
+	KEY_SUBMIT_HEIGHT = "submit"
+	KEY_PENDING       = "pending"
+	KEY_HASH          = "hash"
+	KEY_RESET         = "reset"
+)
+
+// Store is the local on-disk state store for header sync progress
+type Store struct {
+	db   *leveldb.DB
+	path string
+}
+
+func Open(path string) (s *Store, err error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Open state store %s error %v", path, err)
+	}
+	return &Store{db: db, path: path}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// State returns the header sync state of the direction from chain to the dst chain
+func (s *Store) State(chain, dst uint64) *State {
+	return &State{db: s.db, prefix: fmt.Sprintf("sync/%d/%d/", chain, dst), path: s.path, chain: chain, dst: dst}
+}
+
+type Reset struct {
+	Height uint64
+	Time   int64
+}
+
+// State records the header sync progress of a single direction
+type State struct {
+	sync.Mutex
+	db     *leveldb.DB
+	prefix string
+	path   string
+	chain  uint64
+	dst    uint64
+}
+
+func (s *State) key(name string) []byte {
+	return []byte(s.prefix + name)
+}
+
+func (s *State) heightKey(name string, height uint64) []byte {
+	key := s.key(name + "/")
+	// Big endian height keeps the keys in order
+	return append(key, encodeHeight(height)...)
+}
+
+func encodeHeight(height uint64) []byte {
+	bytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(bytes, height)
+	return bytes
+}
+
+func (s *State) getHeight(name string) (height uint64, err error) {
+	value, err := s.db.Get(s.key(name), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+func (s *State) setHeight(name string, height uint64) error {
+	return s.db.Put(s.key(name), encodeHeight(height), nil)
+}
+
+// Last header height fetched from the source chain
+func (s *State) FetchHeight() (uint64, error) {
+	return s.getHeight(KEY_FETCH_HEIGHT)
+}
+
+func (s *State) SetFetchHeight(height uint64) error {
+	return s.setHeight(KEY_FETCH_HEIGHT, height)
+}
+
+// Last header height confirmed on the destination chain
+func (s *State) SubmitHeight() (uint64, error) {
+	return s.getHeight(KEY_SUBMIT_HEIGHT)
+}
+
+// MarkSubmitted records the header as confirmed on the destination chain
+func (s *State) MarkSubmitted(height uint64, hash []byte) (err error) {
+	batch := new(leveldb.Batch)
+	batch.Put(s.key(KEY_SUBMIT_HEIGHT), encodeHeight(height))
+	batch.Put(s.heightKey(KEY_HASH, height), hash)
+	if height > HASH_WINDOW {
+		batch.Delete(s.heightKey(KEY_HASH, height-HASH_WINDOW))
+	}
+	batch.Delete(s.heightKey(KEY_PENDING, height))
+	return s.db.Write(batch, nil)
+}
+
+// Hash returns the recorded hash of the submitted header, nil if out of the window
+func (s *State) Hash(height uint64) (hash []byte, err error) {
+	hash, err = s.db.Get(s.heightKey(KEY_HASH, height), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return
+}
+
+// AddPending records a submission tx not confirmed yet
+func (s *State) AddPending(height uint64, hash string) error {
+	return s.db.Put(s.heightKey(KEY_PENDING, height), []byte(hash), nil)
+}
+
+func (s *State) RemovePending(height uint64) error {
+	return s.db.Delete(s.heightKey(KEY_PENDING, height), nil)
+}
+
+// Pending returns the pending submission tx hashes by header height
+func (s *State) Pending() (txs map[uint64]string, err error) {
+	txs = map[uint64]string{}
+	prefix := s.key(KEY_PENDING + "/")
+	it := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+	for it.Next() {
+		txs[binary.BigEndian.Uint64(it.Key()[len(prefix):])] = string(it.Value())
+	}
+	return txs, it.Error()
+}
+
+// Resume returns the height to resume fetching from based on the confirmed height, advanced over
+// the fetched headers with submission txs still pending, so they wont be submitted twice.
+func (s *State) Resume(confirmed uint64) (height uint64, err error) {
+	height = confirmed
+	fetched, err := s.FetchHeight()
+	if err != nil || fetched <= height {
+		return
+	}
+	txs, err := s.Pending()
+	if err != nil {
+		return
+	}
+	for height < fetched && txs[height+1] != "" {
+		height++
+	}
+	return
+}
+
+// Rewind records a reset event and drops the state above the height
+func (s *State) Rewind(height uint64) (err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, name := range []string{KEY_HASH, KEY_PENDING} {
+		it := s.db.NewIterator(&util.Range{Start: s.heightKey(name, height+1), Limit: s.heightKey(name, ^uint64(0))}, nil)
+		for it.Next() {
+			batch.Delete(append([]byte{}, it.Key()...))
+		}
+		it.Release()
+		if err = it.Error(); err != nil {
+			return
+		}
+	}
+	for _, name := range []string{KEY_FETCH_HEIGHT, KEY_SUBMIT_HEIGHT} {
+		current, err := s.getHeight(name)
+		if err != nil {
+			return err
+		}
+		if current > height {
+			batch.Put(s.key(name), encodeHeight(height))
+		}
+	}
+
+	resets, err := s.Resets()
+	if err != nil {
+		return
+	}
+	resets = append(resets, Reset{Height: height, Time: time.Now().Unix()})
+	if len(resets) > RESET_WINDOW {
+		resets = resets[len(resets)-RESET_WINDOW:]
+	}
+	data, err := json.Marshal(resets)
+	if err != nil {
+		return
+	}
+	batch.Put(s.key(KEY_RESET), data)
+	return s.db.Write(batch, nil)
+}
+
+// Resets returns the recent reset events
+func (s *State) Resets() (resets []Reset, err error) {
+	data, err := s.db.Get(s.key(KEY_RESET), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &resets)
+	return
+}
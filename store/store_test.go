@@ -0,0 +1,188 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "relayer-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		os.RemoveAll(dir)
+	})
+	return s
+}
+
+func hashAt(height uint64) []byte {
+	return []byte{byte(height >> 8), byte(height)}
+}
+
+func TestMarkSubmitted(t *testing.T) {
+	state := openStore(t).State(1, 0)
+	for height := uint64(1); height <= 3; height++ {
+		if err := state.AddPending(height, "0xtx"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for height := uint64(1); height <= 2; height++ {
+		if err := state.MarkSubmitted(height, hashAt(height)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	submitted, err := state.SubmitHeight()
+	if err != nil || submitted != 2 {
+		t.Fatalf("expect submit height 2, got %d %v", submitted, err)
+	}
+	hash, err := state.Hash(2)
+	if err != nil || !bytes.Equal(hash, hashAt(2)) {
+		t.Fatalf("unexpected hash at 2: %x %v", hash, err)
+	}
+	if hash, _ = state.Hash(3); hash != nil {
+		t.Fatalf("expect no hash at 3, got %x", hash)
+	}
+	pending, err := state.Pending()
+	if err != nil || len(pending) != 1 || pending[3] != "0xtx" {
+		t.Fatalf("expect only height 3 pending, got %v %v", pending, err)
+	}
+}
+
+func TestMarkSubmittedWindow(t *testing.T) {
+	state := openStore(t).State(1, 0)
+	top := uint64(HASH_WINDOW + 10)
+	for height := uint64(1); height <= top; height++ {
+		if err := state.MarkSubmitted(height, hashAt(height)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		height uint64
+		kept   bool
+	}{
+		{1, false},
+		{top - HASH_WINDOW, false},
+		{top - HASH_WINDOW + 1, true},
+		{top, true},
+	}
+	for _, c := range cases {
+		hash, err := state.Hash(c.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (hash != nil) != c.kept {
+			t.Fatalf("height %d expect hash kept %v, got %x", c.height, c.kept, hash)
+		}
+	}
+}
+
+func TestRewind(t *testing.T) {
+	s := openStore(t)
+	state, other := s.State(1, 0), s.State(0, 1)
+	for height := uint64(1); height <= 10; height++ {
+		state.MarkSubmitted(height, hashAt(height))
+		other.MarkSubmitted(height, hashAt(height))
+	}
+	state.AddPending(11, "0x11")
+	state.AddPending(12, "0x12")
+	state.AddPending(6, "0x06")
+	state.SetFetchHeight(12)
+
+	if err := state.Rewind(7); err != nil {
+		t.Fatal(err)
+	}
+	if submitted, _ := state.SubmitHeight(); submitted != 7 {
+		t.Fatalf("expect submit height rewound to 7, got %d", submitted)
+	}
+	if fetched, _ := state.FetchHeight(); fetched != 7 {
+		t.Fatalf("expect fetch height rewound to 7, got %d", fetched)
+	}
+	for height := uint64(1); height <= 10; height++ {
+		hash, _ := state.Hash(height)
+		if (hash != nil) != (height <= 7) {
+			t.Fatalf("height %d unexpected hash %x after rewind", height, hash)
+		}
+	}
+	pending, _ := state.Pending()
+	if len(pending) != 1 || pending[6] != "0x06" {
+		t.Fatalf("expect pending txs above 7 dropped, got %v", pending)
+	}
+	if submitted, _ := other.SubmitHeight(); submitted != 10 {
+		t.Fatalf("expect other direction untouched, got %d", submitted)
+	}
+	if hash, _ := other.Hash(10); hash == nil {
+		t.Fatal("expect other direction hashes kept")
+	}
+
+	// Rewind above the submit height keeps the height
+	if err := state.Rewind(9); err != nil {
+		t.Fatal(err)
+	}
+	if submitted, _ := state.SubmitHeight(); submitted != 7 {
+		t.Fatalf("expect submit height kept at 7, got %d", submitted)
+	}
+	resets, err := state.Resets()
+	if err != nil || len(resets) != 2 || resets[0].Height != 7 || resets[1].Height != 9 {
+		t.Fatalf("unexpected resets %+v %v", resets, err)
+	}
+}
+
+func TestRewindResetWindow(t *testing.T) {
+	state := openStore(t).State(1, 0)
+	for i := 0; i < RESET_WINDOW+5; i++ {
+		if err := state.Rewind(uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resets, err := state.Resets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resets) != RESET_WINDOW || resets[0].Height != 5 {
+		t.Fatalf("expect the last %d resets kept, got %d from %d", RESET_WINDOW, len(resets), resets[0].Height)
+	}
+}
+
+func TestResume(t *testing.T) {
+	cases := []struct {
+		name      string
+		fetched   uint64
+		pending   []uint64
+		confirmed uint64
+		resume    uint64
+	}{
+		{"nothing fetched", 0, nil, 5, 5},
+		{"fetched not submitted", 9, nil, 5, 5},
+		{"pending above confirmed", 9, []uint64{6, 7}, 5, 7},
+		{"pending up to fetched", 7, []uint64{6, 7, 8}, 5, 7},
+		{"gap in pending", 9, []uint64{6, 8}, 5, 6},
+		{"pending not next", 9, []uint64{7, 8}, 5, 5},
+		{"chain ahead of local", 4, []uint64{3, 4}, 5, 5},
+	}
+	for _, c := range cases {
+		state := openStore(t).State(1, 0)
+		if err := state.SetFetchHeight(c.fetched); err != nil {
+			t.Fatal(err)
+		}
+		for _, height := range c.pending {
+			state.AddPending(height, "0xtx")
+		}
+		resume, err := state.Resume(c.confirmed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resume != c.resume {
+			t.Fatalf("%s: expect resume from %d, got %d", c.name, c.resume, resume)
+		}
+	}
+}